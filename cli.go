@@ -0,0 +1,472 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/jsonHelper"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+// 命令行退出码
+const (
+	exitOk = iota
+	exitError
+	exitUsage
+	// exitCheckFailed monitor tail期间有检测失败
+	exitCheckFailed
+)
+
+// cliCommands 第一个参数为子命令时只执行命令 不启动界面与内置服务
+var cliCommands = map[string]bool{"url": true, "proxy": true, "monitor": true}
+
+const cliUsage = `用法:
+  httpMonitorGui [-memory|-local] <命令> [参数]
+
+命令:
+  url list                      列出地址
+  url add [-interval 30s] URL   添加或修改地址
+  url rm URL...                 删除地址
+  proxy list                    列出代理
+  proxy add [-force] PROXY      测试后添加代理 -force跳过测试
+  proxy rm PROXY...             删除代理
+  monitor start                 启动监控并持续输出结果 收到SIGINT/SIGTERM后停止
+  monitor stop                  停止服务端的监控
+  monitor tail [-n 10] [-timeout 1m]
+                                启动监控 输出n个结果后停止 有失败时退出码为3
+
+每个命令都支持 -output json|table
+`
+
+type cli struct {
+	backend rpc.MonitorBackend
+	monitor *rpc.Aggregator
+	bus     *rpc.EventBus
+	labels  *proxy.LabelStore
+	out     io.Writer
+	errOut  io.Writer
+}
+
+// usageError 参数错误 退出码为exitUsage
+type usageError struct {
+	msg string
+}
+
+func (err usageError) Error() string {
+	return err.msg
+}
+
+func runCli(c *cli, args []string) int {
+	if len(args) < 2 {
+		fmt.Fprint(c.errOut, cliUsage)
+		return exitUsage
+	}
+
+	var code int
+	var err error
+	switch args[0] + " " + args[1] {
+	case "url list":
+		err = c.urlList(args[2:])
+	case "url add":
+		err = c.urlAdd(args[2:])
+	case "url rm":
+		err = c.urlRm(args[2:])
+	case "proxy list":
+		err = c.proxyList(args[2:])
+	case "proxy add":
+		err = c.proxyAdd(args[2:])
+	case "proxy rm":
+		err = c.proxyRm(args[2:])
+	case "monitor start":
+		code, err = c.monitorStream(args[2:], false)
+	case "monitor tail":
+		code, err = c.monitorStream(args[2:], true)
+	case "monitor stop":
+		err = c.monitorStop(args[2:])
+	default:
+		err = usageError{"未知命令:" + strings.Join(args[:2], " ")}
+	}
+
+	var usage usageError
+	switch {
+	case errors.As(err, &usage):
+		fmt.Fprintln(c.errOut, err)
+		fmt.Fprint(c.errOut, cliUsage)
+		return exitUsage
+	case err != nil:
+		fmt.Fprintln(c.errOut, "错误:", err)
+		return exitError
+	}
+
+	return code
+}
+
+// flags 子命令参数 都支持-output
+func (c *cli) flags(name string) (*flag.FlagSet, *string) {
+	set := flag.NewFlagSet(name, flag.ContinueOnError)
+	set.SetOutput(io.Discard)
+	output := set.String("output", "table", "输出格式 json或table")
+	return set, output
+}
+
+func (c *cli) parse(set *flag.FlagSet, output *string, args []string) error {
+	if err := set.Parse(args); err != nil {
+		return usageError{set.Name() + ":" + err.Error()}
+	}
+	if *output != "json" && *output != "table" {
+		return usageError{"-output只支持json或table"}
+	}
+	return nil
+}
+
+// print json时输出v table时输出header与rows
+func (c *cli) print(output string, v interface{}, header []string, rows [][]string) error {
+	if output == "json" {
+		text, err := jsonHelper.PrettyStruct(v)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(c.out, text)
+		return err
+	}
+
+	writer := tabwriter.NewWriter(c.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(header, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+	return writer.Flush()
+}
+
+type cliUrl struct {
+	Url        string `json:"url"`
+	Interval   string `json:"interval"`
+	IntervalMs int32  `json:"intervalMs"`
+}
+
+func (c *cli) urlList(args []string) error {
+	set, output := c.flags("url list")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	urlInterval, err := c.backend.ListUrlInterval()
+	if err != nil {
+		return err
+	}
+
+	urls := make([]cliUrl, 0, len(urlInterval))
+	for url, interval := range urlInterval {
+		urls = append(urls, cliUrl{Url: url, Interval: rpc.FormatInterval(interval), IntervalMs: interval})
+	}
+	sort.Slice(urls, func(i, j int) bool {
+		return urls[i].Url < urls[j].Url
+	})
+	rows := make([][]string, 0, len(urls))
+	for _, u := range urls {
+		rows = append(rows, []string{u.Url, u.Interval})
+	}
+
+	return c.print(*output, urls, []string{"URL", "INTERVAL"}, rows)
+}
+
+func (c *cli) urlAdd(args []string) error {
+	set, output := c.flags("url add")
+	intervalText := set.String("interval", rpc.DefaultInterval.String(), "检测间隔 毫秒或时长")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	if set.NArg() != 1 {
+		return usageError{"url add需要一个地址"}
+	}
+	url := set.Arg(0)
+	if err := rpc.ValidateUrl(url); err != nil {
+		return err
+	}
+	interval, err := rpc.ParseInterval(*intervalText)
+	if err != nil {
+		return err
+	}
+	if err = c.backend.SetUrl(url, interval); err != nil {
+		return err
+	}
+
+	u := cliUrl{Url: url, Interval: rpc.FormatInterval(interval), IntervalMs: interval}
+	return c.print(*output, u, []string{"URL", "INTERVAL"}, [][]string{{u.Url, u.Interval}})
+}
+
+// urlRm 地址不存在时报错 已删除的地址不回滚
+func (c *cli) urlRm(args []string) error {
+	set, output := c.flags("url rm")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	if set.NArg() == 0 {
+		return usageError{"url rm需要至少一个地址"}
+	}
+	urlInterval, err := c.backend.ListUrlInterval()
+	if err != nil {
+		return err
+	}
+
+	var removed []string
+	for _, url := range set.Args() {
+		if _, ok := urlInterval[url]; !ok {
+			return errors.New("地址不存在:" + url)
+		}
+		if err = c.backend.DeleteUrl(url); err != nil {
+			return err
+		}
+		removed = append(removed, url)
+	}
+
+	return c.printRemoved(*output, removed)
+}
+
+func (c *cli) printRemoved(output string, removed []string) error {
+	rows := make([][]string, 0, len(removed))
+	for _, v := range removed {
+		rows = append(rows, []string{v})
+	}
+	return c.print(output, map[string][]string{"removed": removed}, []string{"REMOVED"}, rows)
+}
+
+// cliProxy 输出的代理地址隐藏密码
+type cliProxy struct {
+	Proxy  string `json:"proxy"`
+	Name   string `json:"name,omitempty"`
+	Region string `json:"region,omitempty"`
+	Group  string `json:"group,omitempty"`
+}
+
+func (c *cli) proxyList(args []string) error {
+	set, output := c.flags("proxy list")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	raws, err := c.backend.ListProxy()
+	if err != nil {
+		return err
+	}
+
+	proxies := make([]cliProxy, 0, len(raws))
+	rows := make([][]string, 0, len(raws))
+	for _, raw := range raws {
+		label := c.labels.Get(raw)
+		proxies = append(proxies, cliProxy{Proxy: proxy.Redact(raw), Name: label.Name, Region: label.Region, Group: label.Group})
+		rows = append(rows, []string{proxy.Redact(raw), label.Name, label.Region, label.Group})
+	}
+
+	return c.print(*output, proxies, []string{"PROXY", "NAME", "REGION", "GROUP"}, rows)
+}
+
+type cliProxyTest struct {
+	Proxy     string `json:"proxy"`
+	Tested    bool   `json:"tested"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+	EgressIp  string `json:"egressIp,omitempty"`
+}
+
+// proxyAdd 与界面一致 测试失败时拒绝保存 除非-force
+func (c *cli) proxyAdd(args []string) error {
+	set, output := c.flags("proxy add")
+	force := set.Bool("force", false, "跳过连接测试")
+	probeUrl := set.String("probe", proxy.DefaultProbeUrl, "测试代理时访问的地址")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	if set.NArg() != 1 {
+		return usageError{"proxy add需要一个代理"}
+	}
+	raw := strings.TrimSpace(set.Arg(0))
+	if _, err := proxy.Parse(raw); err != nil {
+		return err
+	}
+
+	added := cliProxyTest{Proxy: proxy.Redact(raw)}
+	if !*force {
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		res := proxy.Test(ctx, raw, *probeUrl)
+		cancel()
+		if res.Err != nil {
+			return errors.New("代理不可用 " + res.String() + " 使用-force强制添加")
+		}
+		added.Tested, added.LatencyMs, added.EgressIp = true, res.Latency.Milliseconds(), res.EgressIp
+	}
+	if err := c.backend.SetProxy(raw); err != nil {
+		return err
+	}
+
+	row := []string{proxy.Redact(raw), "-", "-"}
+	if added.Tested {
+		row = []string{proxy.Redact(raw), (time.Duration(added.LatencyMs) * time.Millisecond).String(), added.EgressIp}
+	}
+	return c.print(*output, added, []string{"PROXY", "LATENCY", "EGRESS_IP"}, [][]string{row})
+}
+
+func (c *cli) proxyRm(args []string) error {
+	set, output := c.flags("proxy rm")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	if set.NArg() == 0 {
+		return usageError{"proxy rm需要至少一个代理"}
+	}
+	raws, err := c.backend.ListProxy()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]bool, len(raws))
+	for _, raw := range raws {
+		existing[raw] = true
+	}
+
+	var removed []string
+	for _, raw := range set.Args() {
+		if !existing[raw] {
+			return errors.New("代理不存在:" + raw)
+		}
+		if err = c.backend.DeleteProxy(raw); err != nil {
+			return err
+		}
+		if err = c.labels.Delete(raw); err != nil {
+			return err
+		}
+		removed = append(removed, proxy.Redact(raw))
+	}
+
+	return c.printRemoved(*output, removed)
+}
+
+func (c *cli) monitorStop(args []string) error {
+	set, output := c.flags("monitor stop")
+	if err := c.parse(set, output, args); err != nil {
+		return err
+	}
+	if err := c.backend.StopMonitor(); err != nil {
+		return err
+	}
+	return c.print(*output, map[string]bool{"stopped": true}, []string{"STOPPED"}, [][]string{{"true"}})
+}
+
+// resultRow 一个代理上的检测结果 Proxy为原始地址 ProxyName为显示名称
+type resultRow struct {
+	Time      time.Time `json:"time"`
+	Source    string    `json:"source"`
+	Url       string    `json:"url"`
+	Proxy     string    `json:"proxy"`
+	ProxyName string    `json:"proxyName"`
+	Status    string    `json:"status"`
+	Ok        bool      `json:"ok"`
+	Message   string    `json:"message"`
+}
+
+// monitorStream 启动监控并输出结果 tail为true时输出n个结果或超时后停止
+// json时每个结果一行 便于逐行处理
+func (c *cli) monitorStream(args []string, tail bool) (int, error) {
+	set, output := c.flags("monitor")
+	n := set.Int("n", 10, "tail输出的结果数")
+	timeout := set.Duration("timeout", time.Minute, "tail最长等待时间")
+	if err := c.parse(set, output, args); err != nil {
+		return exitOk, err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if tail {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	results := c.bus.Subscribe("cli", 1000)
+	defer results.Unsubscribe()
+	if err := startHeadless(ctx, c.monitor, c.bus); err != nil {
+		return exitOk, err
+	}
+	defer c.monitor.StopMonitor()
+
+	//结果逐条输出 无法预先对齐 table时以tab分隔
+	table := *output == "table"
+	if table {
+		fmt.Fprintln(c.out, "TIME\tSOURCE\tURL\tPROXY\tSTATUS")
+	}
+
+	count, failed := 0, false
+	code := func() int {
+		if tail && failed {
+			return exitCheckFailed
+		}
+		return exitOk
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			if tail && count == 0 {
+				return exitOk, errors.New("等待监控结果超时")
+			}
+			return code(), nil
+		case res, ok := <-results.C:
+			if !ok {
+				return code(), nil
+			}
+			if res.MonitorResponse == nil || res.Url == "" {
+				continue
+			}
+			for _, r := range resultRows(res, c.labels.Name) {
+				failed = failed || !r.Ok
+				if table {
+					fmt.Fprintln(c.out, strings.Join([]string{r.Time.Format(time.RFC3339), r.Source, r.Url, r.ProxyName, r.Status}, "\t"))
+				} else {
+					data, err := json.Marshal(r)
+					if err != nil {
+						return exitOk, err
+					}
+					fmt.Fprintln(c.out, string(data))
+				}
+			}
+			count++
+			if tail && count >= *n {
+				return code(), nil
+			}
+		}
+	}
+}
+
+// resultRows 每个代理一个结果 直连在前
+func resultRows(res *rpc.MonitorResult, labeler status.Labeler) []resultRow {
+	proxies := make([]string, 0, len(res.Result))
+	for p := range res.Result {
+		proxies = append(proxies, p)
+	}
+	sort.Strings(proxies)
+
+	rows := make([]resultRow, 0, len(proxies))
+	for _, p := range proxies {
+		r := result.Parse(res.Result[p])
+		rows = append(rows, resultRow{
+			Time:      res.Time,
+			Source:    res.Source,
+			Url:       res.Url,
+			Proxy:     p,
+			ProxyName: labeler.Proxy(p),
+			Status:    r.String(),
+			Ok:        r.OK(),
+			Message:   res.Result[p],
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,182 @@
+package component
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/layouts"
+	"github.com/flyflyhe/httpMonitorGui/services/alert"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+func alertScreen(w fyne.Window, rules *alert.RuleStore, engine *alert.Engine) fyne.CanvasObject {
+	vBox := container.New(layouts.NewVBoxLayout())
+
+	var addButton *widget.Button
+	var showButton *widget.Button
+	var alertButton *widget.Button
+	var showButtonFunc func()
+
+	ruleForm := func(rule alert.Rule, isAdd bool) *widget.Form {
+		nameEntry := widget.NewEntry()
+		var kindNames []string
+		for _, kind := range alert.RuleKinds {
+			kindNames = append(kindNames, kind.String())
+		}
+		kindSelect := widget.NewSelect(kindNames, nil)
+		countEntry := widget.NewEntry()
+		ofEntry := widget.NewEntry()
+		ofEntry.SetPlaceHolder("为空只比较失败代理数")
+		durationEntry := widget.NewEntry()
+		durationEntry.SetPlaceHolder("eg:5m")
+
+		reset := func() {
+			nameEntry.SetText(rule.Name)
+			kindSelect.SetSelectedIndex(int(rule.Kind))
+			countEntry.SetText(strconv.Itoa(rule.Count))
+			ofEntry.SetText("")
+			if rule.Of > 0 {
+				ofEntry.SetText(strconv.Itoa(rule.Of))
+			}
+			durationEntry.SetText("")
+			if rule.Duration > 0 {
+				durationEntry.SetText(rule.Duration.String())
+			}
+		}
+		reset()
+		if !isAdd {
+			nameEntry.Disable()
+		}
+
+		return &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "名称", Widget: nameEntry},
+				{Text: "类型", Widget: kindSelect},
+				{Text: "失败次数/代理数", Widget: countEntry},
+				{Text: "代理总数", Widget: ofEntry},
+				{Text: "持续时间", Widget: durationEntry},
+			},
+			OnCancel:   reset,
+			CancelText: "重置",
+			OnSubmit: func() {
+				if isAdd {
+					if _, ok := rules.Get(nameEntry.Text); ok {
+						dialog.ShowInformation("提示", "规则已存在:"+nameEntry.Text, w)
+						return
+					}
+				}
+				r := alert.Rule{Name: nameEntry.Text, Kind: alert.RuleKinds[kindSelect.SelectedIndex()]}
+				var err error
+				if countEntry.Text != "" {
+					if r.Count, err = strconv.Atoi(countEntry.Text); err != nil {
+						dialog.ShowError(errors.New("失败次数必须是整数"), w)
+						return
+					}
+				}
+				if ofEntry.Text != "" {
+					if r.Of, err = strconv.Atoi(ofEntry.Text); err != nil {
+						dialog.ShowError(errors.New("代理总数必须是整数"), w)
+						return
+					}
+				}
+				if durationEntry.Text != "" {
+					if r.Duration, err = time.ParseDuration(durationEntry.Text); err != nil {
+						dialog.ShowError(errors.New("持续时间格式错误 eg:5m"), w)
+						return
+					}
+				}
+
+				if err = rules.Save(r); err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "保存成功", w)
+				}
+			},
+			SubmitText: "保存",
+		}
+	}
+
+	addButton = widget.NewButton("添加", func() {
+		buttonFocusLost(addButton, showButton, alertButton)
+		addButton.FocusGained()
+
+		form := ruleForm(alert.Rule{Kind: alert.RuleConsecutive, Count: 3}, true)
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form)}
+		vBox.Refresh()
+	})
+
+	showRule := func(rule alert.Rule) {
+		deleteButton := widget.NewButton("删除", func() {
+			dialog.ShowConfirm("操作", "是否删除", func(b bool) {
+				if b {
+					if err := rules.Delete(rule.Name); err != nil {
+						dialog.ShowError(err, w)
+					} else {
+						dialog.ShowInformation("提示", "删除成功", w)
+						showButtonFunc()
+					}
+				}
+			}, w)
+		})
+
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(ruleForm(rule, false), container.NewHBox(deleteButton))}
+		vBox.Refresh()
+	}
+
+	showButtonFunc = func() {
+		buttonFocusLost(addButton, showButton, alertButton)
+		showButton.FocusGained()
+
+		list := rules.List()
+		listWidget := widget.NewList(
+			func() int {
+				return len(list)
+			},
+			func() fyne.CanvasObject {
+				return widget.NewLabel("template")
+			},
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				o.(*widget.Label).SetText(list[i].Name + "--" + list[i].Describe())
+			})
+		listWidget.OnSelected = func(id widget.ListItemID) {
+			showRule(list[id])
+		}
+
+		layouts.SetObjConfigMap(listWidget, &layouts.Size{Height: 400, Width: 200})
+		vBox.Objects = []fyne.CanvasObject{container.New(layouts.NewVBoxLayout(), listWidget)}
+		vBox.Refresh()
+	}
+	showButton = widget.NewButton("规则", showButtonFunc)
+
+	alertButton = widget.NewButton("当前告警", func() {
+		buttonFocusLost(addButton, showButton, alertButton)
+		alertButton.FocusGained()
+
+		alerts := engine.Alerts()
+		listWidget := widget.NewList(
+			func() int {
+				return len(alerts)
+			},
+			func() fyne.CanvasObject {
+				return widget.NewLabel("template")
+			},
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				a := alerts[i]
+				o.(*widget.Label).SetText("[" + a.Rule + "]" + a.Title() + " " + status.Since(a.Since, time.Now()) + "开始")
+			})
+		listWidget.OnSelected = func(id widget.ListItemID) {
+			dialog.ShowInformation(alerts[id].Title(), alerts[id].Summary(), w)
+		}
+
+		layouts.SetObjConfigMap(listWidget, &layouts.Size{Height: 400, Width: 200})
+		vBox.Objects = []fyne.CanvasObject{container.New(layouts.NewVBoxLayout(), listWidget)}
+		vBox.Refresh()
+	})
+
+	return container.NewVBox(container.NewHBox(showButton, addButton, alertButton), widget.NewSeparator(), vBox)
+}
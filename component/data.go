@@ -1,7 +1,16 @@
 package component
 
 import (
+	"strings"
+	"sync"
+
 	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/alert"
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/history"
+	"github.com/flyflyhe/httpMonitorGui/services/notify"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
 )
 
 type AppView struct {
@@ -16,14 +25,120 @@ var (
 			"See the canvas capabilities.",
 			canvasScreen,
 		},
-		"url":     {Title: "地址管理", View: urlScreen},
-		"proxy":   {Title: "代理管理", View: proxyScreen},
-		"monitor": {Title: "监控管理", View: monitorScreen},
 	}
 
 	//index tree
 
 	AppViewsIndex = map[string][]string{
-		"": {"welcome", "url", "proxy", "monitor"},
+		"": {"welcome", "url", "proxy", "monitor", "alert", "silence", "notify", "profile"},
 	}
 )
+
+// viewClosers 当前页面注册的清理函数 切换页面时执行
+var (
+	viewClosersLock sync.Mutex
+	viewClosers     []func()
+)
+
+// onViewClosed 注册当前页面被替换时的清理 如取消订阅与移除数据监听
+func onViewClosed(f func()) {
+	viewClosersLock.Lock()
+	defer viewClosersLock.Unlock()
+	viewClosers = append(viewClosers, f)
+}
+
+// CloseView 执行并清空当前页面注册的清理 main在替换或关闭页面时调用
+func CloseView() {
+	viewClosersLock.Lock()
+	closers := viewClosers
+	viewClosers = nil
+	viewClosersLock.Unlock()
+
+	for _, f := range closers {
+		f()
+	}
+}
+
+// Services 页面依赖的服务 由main注入
+type Services struct {
+	Backend     rpc.MonitorBackend
+	Profiles    *rpc.ProfileStore
+	Conns       *rpc.ConnManager
+	Monitor     *rpc.Aggregator
+	Bus         *rpc.EventBus
+	History     *history.Store
+	Rules       *alert.RuleStore
+	Alerts      *alert.Engine
+	Silences    *alert.SilenceStore
+	Channels    *notify.Store
+	Groups      *group.Store
+	ProxyLabels *proxy.LabelStore
+}
+
+// InitAppViews 注册依赖服务的页面 必须在构建导航前调用
+func InitAppViews(services *Services) {
+	AppViews["url"] = AppView{Title: "地址管理", View: func(w fyne.Window) fyne.CanvasObject {
+		return urlScreen(w, services.Backend, services.Groups, group.Filter{})
+	}}
+	AppViews["proxy"] = AppView{Title: "代理管理", View: func(w fyne.Window) fyne.CanvasObject {
+		return proxyScreen(w, services.Backend, services.ProxyLabels)
+	}}
+	AppViews["monitor"] = AppView{Title: "监控管理", View: func(w fyne.Window) fyne.CanvasObject {
+		return monitorScreen(w, services.Monitor, services.Bus, services.Groups, services.ProxyLabels.Name)
+	}}
+	AppViews["alert"] = AppView{Title: "告警规则", View: func(w fyne.Window) fyne.CanvasObject {
+		return alertScreen(w, services.Rules, services.Alerts)
+	}}
+	AppViews["silence"] = AppView{Title: "维护窗口", View: func(w fyne.Window) fyne.CanvasObject {
+		return silenceScreen(w, services.Silences, services.ProxyLabels.Name)
+	}}
+	AppViews["notify"] = AppView{Title: "通知渠道", View: func(w fyne.Window) fyne.CanvasObject {
+		return notifyScreen(w, services.Channels)
+	}}
+	AppViews["profile"] = AppView{Title: "连接配置", View: func(w fyne.Window) fyne.CanvasObject {
+		return profileScreen(w, services.Profiles, services.Conns)
+	}}
+	refreshGroupViews(services)
+	services.Groups.SetOnChanged(func() {
+		refreshGroupViews(services)
+		if onAppViewsChanged != nil {
+			onAppViewsChanged()
+		}
+	})
+}
+
+// onAppViewsChanged 分组变化后通知导航树刷新
+var onAppViewsChanged func()
+
+// SetOnAppViewsChanged 页面索引变化时回调 由导航树注册
+func SetOnAppViewsChanged(f func()) {
+	onAppViewsChanged = f
+}
+
+// refreshGroupViews 地址管理下每个分组和标签一个子页面 uid为url/group/名称 url/tag/名称
+func refreshGroupViews(services *Services) {
+	for uid := range AppViews {
+		if strings.HasPrefix(uid, "url/") {
+			delete(AppViews, uid)
+		}
+	}
+
+	var children []string
+	addView := func(uid, title string, filter group.Filter) {
+		children = append(children, uid)
+		AppViews[uid] = AppView{Title: title, View: func(w fyne.Window) fyne.CanvasObject {
+			return urlScreen(w, services.Backend, services.Groups, filter)
+		}}
+	}
+	groups := services.Groups.Groups()
+	for _, name := range groups {
+		addView("url/group/"+name, name, group.Filter{Group: name})
+	}
+	if len(groups) > 0 {
+		addView("url/group/"+group.Ungrouped, group.Ungrouped, group.Filter{Group: group.Ungrouped})
+	}
+	for _, tag := range services.Groups.Tags() {
+		addView("url/tag/"+tag, "#"+tag, group.Filter{Tag: tag})
+	}
+	AppViewsIndex["url"] = children
+}
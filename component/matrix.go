@@ -0,0 +1,80 @@
+package component
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+// visibleUrls visible为nil时返回全部url
+func visibleUrls(matrix *status.Matrix, visible func(url string) bool) []string {
+	urls := matrix.Urls()
+	if visible == nil {
+		return urls
+	}
+	filtered := urls[:0]
+	for _, url := range urls {
+		if visible(url) {
+			filtered = append(filtered, url)
+		}
+	}
+	return filtered
+}
+
+// matrixTable 状态矩阵表格 第一行为代理 第一列为url 点击单元格显示原始信息 只显示visible的url
+func matrixTable(w fyne.Window, matrix *status.Matrix, labeler status.Labeler, visible func(url string) bool) *widget.Table {
+	table := widget.NewTable(
+		func() (int, int) {
+			return len(visibleUrls(matrix, visible)) + 1, len(matrix.Columns()) + 1
+		},
+		func() fyne.CanvasObject {
+			return canvas.NewText("连接被拒绝 59分钟前", theme.ForegroundColor())
+		},
+		func(id widget.TableCellID, obj fyne.CanvasObject) {
+			text := obj.(*canvas.Text)
+			text.Text = ""
+			text.Color = theme.ForegroundColor()
+			defer text.Refresh()
+
+			//长度与内容分开读取 期间可能新增行列
+			urls, columns := visibleUrls(matrix, visible), matrix.Columns()
+			if id.Row > len(urls) || id.Col > len(columns) {
+				return
+			}
+			switch {
+			case id.Row == 0 && id.Col == 0:
+				text.Text = "地址"
+			case id.Row == 0:
+				text.Text = labeler.Title(columns[id.Col-1], matrix.MultiSource())
+			case id.Col == 0:
+				text.Text = urls[id.Row-1]
+			default:
+				cell, ok := matrix.Cell(urls[id.Row-1], columns[id.Col-1])
+				if !ok {
+					text.Text = "-"
+					return
+				}
+				text.Text = cell.String() + " " + status.Since(cell.Time, time.Now())
+				text.Color = resultColor(cell.Result)
+			}
+		})
+	table.SetColumnWidth(0, 220)
+
+	table.OnSelected = func(id widget.TableCellID) {
+		defer table.UnselectAll()
+		urls, columns := visibleUrls(matrix, visible), matrix.Columns()
+		if id.Row < 1 || id.Col < 1 || id.Row > len(urls) || id.Col > len(columns) {
+			return
+		}
+		if cell, ok := matrix.Cell(urls[id.Row-1], columns[id.Col-1]); ok {
+			dialog.ShowInformation(urls[id.Row-1], labeler.Title(columns[id.Col-1], true)+"\n"+cell.String()+"\n"+cell.Message+"\n"+cell.Time.Format("2006-01-02 15:04:05"), w)
+		}
+	}
+
+	return table
+}
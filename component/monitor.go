@@ -2,26 +2,158 @@ package component
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/flyflyhe/httpMonitorGui/layouts"
-	"github.com/flyflyhe/httpMonitorGui/services/global"
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
 	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
 	"github.com/rs/zerolog/log"
 	"runtime/debug"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
-func monitorScreen(w fyne.Window) fyne.CanvasObject {
+// monitorErrors 合并各服务的错误 key为空表示与具体服务无关
+func monitorErrors(errs map[string]error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+
+	names := make([]string, 0, len(errs))
+	for name := range errs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	msg := ""
+	for _, name := range names {
+		if name != "" {
+			msg += name + ":"
+		}
+		msg += errs[name].Error() + "\n"
+	}
+
+	return errors.New(msg)
+}
+
+// groupFilterOptions 筛选下拉选项 分组名与#标签
+func groupFilterOptions(groups *group.Store) []string {
+	options := []string{"全部"}
+	if names := groups.Groups(); len(names) > 0 {
+		options = append(options, names...)
+		options = append(options, group.Ungrouped)
+	}
+	for _, tag := range groups.Tags() {
+		options = append(options, "#"+tag)
+	}
+	return options
+}
+
+func parseGroupFilter(option string) group.Filter {
+	switch {
+	case option == "全部" || option == "":
+		return group.Filter{}
+	case strings.HasPrefix(option, "#"):
+		return group.Filter{Tag: option[1:]}
+	}
+	return group.Filter{Group: option}
+}
+
+// groupHealth 每个分组的汇总状态 没有分组时返回全部url的状态
+func groupHealth(matrix *status.Matrix, index map[string]group.Meta) string {
+	urls := matrix.Urls()
+	names := make(map[string]bool)
+	for _, url := range urls {
+		meta, ok := index[url]
+		if !ok {
+			meta.Url = url
+		}
+		names[meta.GroupName()] = true
+	}
+	if len(names) <= 1 {
+		return matrix.Health(urls, nil).String()
+	}
+
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+	var parts []string
+	for _, name := range sorted {
+		filter := group.Filter{Group: name}
+		health := matrix.Health(urls, func(url string) bool {
+			return filter.MatchUrl(index, url)
+		})
+		parts = append(parts, name+":"+health.String())
+	}
+	return strings.Join(parts, "  ")
+}
+
+func monitorScreen(w fyne.Window, monitor *rpc.Aggregator, bus *rpc.EventBus, groups *group.Store, labeler status.Labeler) fyne.CanvasObject {
 	vBox := container.New(layouts.NewVBoxLayout())
 
+	//按分组或标签筛选矩阵 index在筛选变化和每秒刷新时重新读取
+	var filterLock sync.RWMutex
+	filter := group.Filter{}
+	index := groups.Index()
+	visible := func(url string) bool {
+		filterLock.RLock()
+		defer filterLock.RUnlock()
+		return filter.MatchUrl(index, url)
+	}
+	filterSelect := widget.NewSelect(groupFilterOptions(groups), func(option string) {
+		filterLock.Lock()
+		defer filterLock.Unlock()
+		filter = parseGroupFilter(option)
+		index = groups.Index()
+	})
+	filterSelect.SetSelectedIndex(0)
+
+	sourceGroup := widget.NewCheckGroup(monitor.Sources(), nil)
+	sourceGroup.Horizontal = true
+	if running := monitor.Running(); len(running) > 0 {
+		sourceGroup.SetSelected(running)
+	} else if sources := monitor.Sources(); len(sources) > 0 {
+		sourceGroup.SetSelected(sources[:1])
+	}
+
 	var startButton *widget.Button
 	var startButtonLock sync.Mutex
 	var stopButton *widget.Button
-	startFunc := func() {
+	//当前页面的订阅 重新启动或监控停止时取消
+	var sub *rpc.Subscription
+	var subLock sync.Mutex
+	//服务状态标签绑定了会话状态 重新启动或离开页面时解除绑定
+	var bound []*widget.Label
+	unsubscribe := func() {
+		subLock.Lock()
+		defer subLock.Unlock()
+		if sub != nil {
+			sub.Unsubscribe()
+			sub = nil
+		}
+		for _, label := range bound {
+			label.Unbind()
+		}
+		bound = nil
+	}
+	startFunc := func(sources []string) {
 		startButton.FocusGained()
+		unsubscribe()
+		subLock.Lock()
+		results := bus.Subscribe("monitorScreen", 100)
+		sub = results
+		subLock.Unlock()
 		go func() {
 			defer func() {
 				if err := recover(); err != nil {
@@ -30,25 +162,69 @@ func monitorScreen(w fyne.Window) fyne.CanvasObject {
 					log.Error().Caller().Msg(string(errJson))
 				}
 			}()
-			entry := widget.NewMultiLineEntry()
-			layouts.SetObjConfigMap(entry, &layouts.Size{Height: 400, Width: 200})
+
+			//每个服务一行状态 下方为url×代理状态矩阵
+			matrix := status.NewMatrix()
+			table := matrixTable(w, matrix, labeler, visible)
+			healthLabel := widget.NewLabel("")
+			healthLabel.Wrapping = fyne.TextWrapWord
+			headers := container.NewVBox()
+			summaries := make(map[string]*widget.Label, len(sources))
+			stats := make(map[string]*result.Stats, len(sources))
+			for _, source := range sources {
+				summaries[source] = widget.NewLabel("")
+				stats[source] = &result.Stats{}
+				header := container.NewHBox(widget.NewLabel(source))
+				if session := monitor.Session(source); session != nil {
+					label := widget.NewLabelWithData(session.StateText())
+					subLock.Lock()
+					if sub == results {
+						bound = append(bound, label)
+					} else {
+						//已取消订阅
+						label.Unbind()
+					}
+					subLock.Unlock()
+					header.Add(label)
+				}
+				header.Add(summaries[source])
+				headers.Add(header)
+			}
+			headers.Add(healthLabel)
+			content := container.NewBorder(headers, nil, nil, nil, table)
+			layouts.SetObjConfigMap(content, &layouts.Size{Height: 400, Width: 600})
+			vBox.Objects = []fyne.CanvasObject{content}
+			vBox.Refresh()
+
+			//每秒刷新距上次检测的时间
+			ticker := time.NewTicker(time.Second)
+			defer ticker.Stop()
 			for {
 				select {
-				case res := <-rpc.GetMonitorQueue().Queue:
-					if res != nil {
-						entry.Text = entry.Text + "\n" + res.Url
-						for proxy, v := range res.Result {
-							entry.Text += "\n" + proxy + "<=>" + v
-							if v != "success" {
-								global.TopFyneApp.SendNotification(fyne.NewNotification(res.Url+"监控异常", "代理"+proxy+"信息+"+v))
+				case res, ok := <-results.C:
+					if !ok {
+						return
+					}
+					if res != nil && res.MonitorResponse != nil {
+						for _, v := range res.Result {
+							if s, ok := stats[res.Source]; ok {
+								s.Add(result.Parse(v))
 							}
 						}
+						if s, ok := stats[res.Source]; ok {
+							summaries[res.Source].SetText(fmt.Sprintf("可用率%.1f%% 失败%d", s.Availability()*100, s.Failed()))
+						}
 
-						vBox.Objects = []fyne.CanvasObject{entry}
-						vBox.Refresh()
+						matrix.Update(res)
+						table.Refresh()
 					}
-
-				default:
+				case <-ticker.C:
+					current := groups.Index()
+					filterLock.Lock()
+					index = current
+					filterLock.Unlock()
+					healthLabel.SetText(groupHealth(matrix, current))
+					table.Refresh()
 				}
 			}
 		}()
@@ -61,11 +237,15 @@ func monitorScreen(w fyne.Window) fyne.CanvasObject {
 
 		dialog.ShowConfirm("url监控", "确认启动", func(b bool) {
 			if b {
-				if err := rpc.StartMonitor(rpc.GetMonitorQueue()); err != nil {
-					dialog.ShowError(err, w)
+				errs := monitor.StartMonitor(bus, sourceGroup.Selected)
+				if running := monitor.Running(); len(running) == 0 {
+					dialog.ShowError(monitorErrors(errs), w)
+				} else if len(errs) > 0 {
+					dialog.ShowError(monitorErrors(errs), w)
+					startFunc(running)
 				} else {
 					dialog.ShowInformation("启动结果", "成功", w)
-					startFunc()
+					startFunc(running)
 				}
 			}
 		}, w)
@@ -77,8 +257,8 @@ func monitorScreen(w fyne.Window) fyne.CanvasObject {
 
 		dialog.ShowConfirm("url监控", "确认停止", func(b bool) {
 			if b {
-				if err := rpc.StopMonitor(rpc.GetMonitorQueue()); err != nil {
-					dialog.ShowError(err, w)
+				if errs := monitor.StopMonitor(); len(errs) > 0 {
+					dialog.ShowError(monitorErrors(errs), w)
 				} else {
 					dialog.ShowInformation("确认停止", "停止成功", w)
 				}
@@ -86,9 +266,29 @@ func monitorScreen(w fyne.Window) fyne.CanvasObject {
 		}, w)
 	})
 
-	if rpc.GetMonitorQueue().Running {
-		startFunc()
+	//按钮与状态跟随会话状态
+	refreshButtons := func() {
+		if monitor.State().Active() {
+			startButton.Disable()
+			stopButton.Enable()
+		} else {
+			startButton.Enable()
+			stopButton.Disable()
+			unsubscribe()
+		}
+	}
+	stateListener := binding.NewDataListener(refreshButtons)
+	monitor.StateText().AddListener(stateListener)
+	stateLabel := widget.NewLabelWithData(monitor.StateText())
+	onViewClosed(func() {
+		monitor.StateText().RemoveListener(stateListener)
+		stateLabel.Unbind()
+		unsubscribe()
+	})
+
+	if running := monitor.Running(); len(running) > 0 {
+		startFunc(running)
 	}
 
-	return container.NewVBox(container.NewHBox(startButton, stopButton), widget.NewSeparator(), vBox)
+	return container.NewVBox(container.NewHBox(startButton, stopButton, stateLabel), container.NewHBox(sourceGroup, widget.NewLabel("筛选"), filterSelect), widget.NewSeparator(), vBox)
 }
@@ -0,0 +1,211 @@
+package component
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/layouts"
+	"github.com/flyflyhe/httpMonitorGui/services/notify"
+)
+
+func notifyScreen(w fyne.Window, channels *notify.Store) fyne.CanvasObject {
+	vBox := container.New(layouts.NewVBoxLayout())
+
+	var addButton *widget.Button
+	var showButton *widget.Button
+	var showButtonFunc func()
+
+	channelForm := func(channel notify.Channel, isAdd bool) (*widget.Form, func() notify.Channel) {
+		nameEntry := widget.NewEntry()
+		var kindNames []string
+		for _, kind := range notify.Kinds {
+			kindNames = append(kindNames, kind.String())
+		}
+		kindSelect := widget.NewSelect(kindNames, nil)
+		enabledCheck := widget.NewCheck("启用", nil)
+		urlEntry := widget.NewEntry()
+		urlEntry.SetPlaceHolder("机器人webhook地址 或env:环境变量名")
+		secretEntry := widget.NewPasswordEntry()
+		secretEntry.SetPlaceHolder("钉钉/飞书加签密钥 可为空 或env:环境变量名")
+		hostEntry := widget.NewEntry()
+		hostEntry.SetPlaceHolder("eg:smtp.qq.com:25")
+		usernameEntry := widget.NewEntry()
+		passwordEntry := widget.NewPasswordEntry()
+		passwordEntry.SetPlaceHolder("明文保存 或env:环境变量名")
+		fromEntry := widget.NewEntry()
+		toEntry := widget.NewEntry()
+		toEntry.SetPlaceHolder("多个收件人用逗号分隔")
+
+		//邮件与webhook使用不同的字段
+		kindSelect.OnChanged = func(string) {
+			isSmtp := notify.Kinds[kindSelect.SelectedIndex()] == notify.KindSmtp
+			for _, entry := range []*widget.Entry{urlEntry, secretEntry} {
+				if isSmtp {
+					entry.Disable()
+				} else {
+					entry.Enable()
+				}
+			}
+			for _, entry := range []*widget.Entry{hostEntry, usernameEntry, passwordEntry, fromEntry, toEntry} {
+				if isSmtp {
+					entry.Enable()
+				} else {
+					entry.Disable()
+				}
+			}
+		}
+
+		reset := func() {
+			nameEntry.SetText(channel.Name)
+			kindSelect.SetSelectedIndex(0)
+			for i, kind := range notify.Kinds {
+				if kind == channel.Kind {
+					kindSelect.SetSelectedIndex(i)
+				}
+			}
+			enabledCheck.SetChecked(channel.Enabled)
+			urlEntry.SetText(channel.Url)
+			secretEntry.SetText(channel.Secret)
+			hostEntry.SetText(channel.Host)
+			usernameEntry.SetText(channel.Username)
+			passwordEntry.SetText(channel.Password)
+			fromEntry.SetText(channel.From)
+			toEntry.SetText(strings.Join(channel.To, ","))
+		}
+		reset()
+		if !isAdd {
+			nameEntry.Disable()
+		}
+
+		current := func() notify.Channel {
+			var to []string
+			for _, addr := range strings.Split(toEntry.Text, ",") {
+				if addr = strings.TrimSpace(addr); addr != "" {
+					to = append(to, addr)
+				}
+			}
+			return notify.Channel{
+				Name:     nameEntry.Text,
+				Kind:     notify.Kinds[kindSelect.SelectedIndex()],
+				Enabled:  enabledCheck.Checked,
+				Url:      urlEntry.Text,
+				Secret:   secretEntry.Text,
+				Host:     hostEntry.Text,
+				Username: usernameEntry.Text,
+				Password: passwordEntry.Text,
+				From:     fromEntry.Text,
+				To:       to,
+			}
+		}
+
+		return &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "名称", Widget: nameEntry},
+				{Text: "类型", Widget: container.NewHBox(kindSelect, enabledCheck)},
+				{Text: "Webhook地址", Widget: urlEntry},
+				{Text: "加签密钥", Widget: secretEntry},
+				{Text: "SMTP地址", Widget: hostEntry},
+				{Text: "用户名", Widget: usernameEntry},
+				{Text: "密码", Widget: passwordEntry},
+				{Text: "发件人", Widget: fromEntry},
+				{Text: "收件人", Widget: toEntry},
+			},
+			OnCancel:   reset,
+			CancelText: "重置",
+			OnSubmit: func() {
+				if isAdd {
+					if _, ok := channels.Get(nameEntry.Text); ok {
+						dialog.ShowInformation("提示", "渠道已存在:"+nameEntry.Text, w)
+						return
+					}
+				}
+				if err := channels.Save(current()); err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "保存成功", w)
+				}
+			},
+			SubmitText: "保存",
+		}, current
+	}
+
+	//testButton 使用表单当前内容发送测试通知 不需要先保存
+	testButton := func(current func() notify.Channel) *widget.Button {
+		return widget.NewButton("测试", func() {
+			go func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+				defer cancel()
+				err := notify.Send(ctx, current(), notify.Message{Title: "httpMonitor测试通知", Text: "收到这条消息说明通知渠道配置正确", Time: time.Now()})
+				if err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "发送成功", w)
+				}
+			}()
+		})
+	}
+
+	addButton = widget.NewButton("添加", func() {
+		buttonFocusLost(addButton, showButton)
+		addButton.FocusGained()
+
+		form, current := channelForm(notify.Channel{Kind: notify.KindWebhook, Enabled: true}, true)
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form, container.NewHBox(testButton(current)))}
+		vBox.Refresh()
+	})
+
+	showChannel := func(channel notify.Channel) {
+		form, current := channelForm(channel, false)
+		deleteButton := widget.NewButton("删除", func() {
+			dialog.ShowConfirm("操作", "是否删除", func(b bool) {
+				if b {
+					if err := channels.Delete(channel.Name); err != nil {
+						dialog.ShowError(err, w)
+					} else {
+						dialog.ShowInformation("提示", "删除成功", w)
+						showButtonFunc()
+					}
+				}
+			}, w)
+		})
+
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form, container.NewHBox(testButton(current), deleteButton))}
+		vBox.Refresh()
+	}
+
+	showButtonFunc = func() {
+		buttonFocusLost(addButton, showButton)
+		showButton.FocusGained()
+
+		list := channels.List()
+		listWidget := widget.NewList(
+			func() int {
+				return len(list)
+			},
+			func() fyne.CanvasObject {
+				return widget.NewLabel("template")
+			},
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				text := list[i].Name + "--" + list[i].Kind.String()
+				if !list[i].Enabled {
+					text += "(未启用)"
+				}
+				o.(*widget.Label).SetText(text)
+			})
+		listWidget.OnSelected = func(id widget.ListItemID) {
+			showChannel(list[id])
+		}
+
+		layouts.SetObjConfigMap(listWidget, &layouts.Size{Height: 400, Width: 200})
+		vBox.Objects = []fyne.CanvasObject{container.New(layouts.NewVBoxLayout(), listWidget)}
+		vBox.Refresh()
+	}
+	showButton = widget.NewButton("列表", showButtonFunc)
+
+	return container.NewVBox(container.NewHBox(showButton, addButton), widget.NewSeparator(), vBox)
+}
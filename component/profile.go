@@ -0,0 +1,166 @@
+package component
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/layouts"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"log"
+)
+
+// connStateLabel 显示连接状态 未建立连接时显示未连接
+func connStateLabel(conns *rpc.ConnManager, name string) *widget.Label {
+	if conns != nil {
+		if managed := conns.Lookup(name); managed != nil {
+			return widget.NewLabelWithData(managed.StateText())
+		}
+	}
+	return widget.NewLabel("未连接")
+}
+
+func profileScreen(w fyne.Window, profiles *rpc.ProfileStore, conns *rpc.ConnManager) fyne.CanvasObject {
+	vBox := container.New(layouts.NewVBoxLayout())
+
+	var addButton *widget.Button
+	var showButton *widget.Button
+	var showButtonFunc func()
+
+	profileForm := func(profile rpc.Profile, isAdd bool) *widget.Form {
+		nameEntry := widget.NewEntry()
+		addressEntry := widget.NewEntry()
+		serverNameEntry := widget.NewEntry()
+		caEntry := widget.NewEntry()
+		certEntry := widget.NewEntry()
+		keyEntry := widget.NewEntry()
+		caEntry.SetPlaceHolder("为空使用内置证书")
+		certEntry.SetPlaceHolder("为空使用内置证书")
+		keyEntry.SetPlaceHolder("为空使用内置证书")
+
+		reset := func() {
+			nameEntry.SetText(profile.Name)
+			addressEntry.SetText(profile.Address)
+			serverNameEntry.SetText(profile.ServerName)
+			caEntry.SetText(profile.CaFile)
+			certEntry.SetText(profile.CertFile)
+			keyEntry.SetText(profile.KeyFile)
+		}
+		reset()
+		if !isAdd {
+			nameEntry.Disable()
+		}
+
+		return &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "名称", Widget: nameEntry},
+				{Text: "服务地址", Widget: addressEntry},
+				{Text: "证书名称", Widget: serverNameEntry},
+				{Text: "CA证书路径", Widget: caEntry},
+				{Text: "客户端证书路径", Widget: certEntry},
+				{Text: "客户端私钥路径", Widget: keyEntry},
+			},
+			OnCancel:   reset,
+			CancelText: "重置",
+			OnSubmit: func() {
+				log.Println("Form submitted:", nameEntry.Text, addressEntry.Text)
+				if isAdd {
+					if _, ok := profiles.Get(nameEntry.Text); ok {
+						dialog.ShowInformation("提示", "配置已存在:"+nameEntry.Text, w)
+						return
+					}
+				}
+				err := profiles.Save(rpc.Profile{
+					Name:       nameEntry.Text,
+					Address:    addressEntry.Text,
+					ServerName: serverNameEntry.Text,
+					CaFile:     caEntry.Text,
+					CertFile:   certEntry.Text,
+					KeyFile:    keyEntry.Text,
+				})
+				if err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "保存成功", w)
+				}
+			},
+			SubmitText: "保存",
+		}
+	}
+
+	addButton = widget.NewButton("添加", func() {
+		buttonFocusLost(addButton, showButton)
+		addButton.FocusGained()
+
+		form := profileForm(rpc.Profile{ServerName: rpc.DefaultProfile.ServerName}, true)
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form)}
+		vBox.Refresh()
+	})
+
+	showProfile := func(profile rpc.Profile) {
+		activeButton := widget.NewButton("启用", func() {
+			if err := profiles.SetActive(profile.Name); err != nil {
+				dialog.ShowError(err, w)
+			} else {
+				dialog.ShowInformation("提示", "已切换到"+profile.Name, w)
+				showButtonFunc()
+			}
+		})
+		deleteButton := widget.NewButton("删除", func() {
+			dialog.ShowConfirm("操作", "是否删除", func(b bool) {
+				if b {
+					if err := profiles.Delete(profile.Name); err != nil {
+						dialog.ShowError(err, w)
+					} else {
+						dialog.ShowInformation("提示", "删除成功", w)
+						showButtonFunc()
+					}
+				}
+			}, w)
+		})
+
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(
+			profileForm(profile, false),
+			container.NewHBox(activeButton, deleteButton, widget.NewLabel("连接状态:"), connStateLabel(conns, profile.Name)),
+		)}
+		vBox.Refresh()
+	}
+
+	showButtonFunc = func() {
+		buttonFocusLost(addButton, showButton)
+		showButton.FocusGained()
+
+		list := profiles.List()
+		active := profiles.Active()
+		listWidget := widget.NewList(
+			func() int {
+				return len(list)
+			},
+			func() fyne.CanvasObject {
+				return widget.NewLabel("template")
+			},
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				text := list[i].Name + "--" + list[i].Address
+				if list[i].Name == active.Name {
+					text += "(当前)"
+				}
+				if conns != nil {
+					if managed := conns.Lookup(list[i].Name); managed != nil {
+						state, _ := managed.StateText().Get()
+						text += " " + state
+					}
+				}
+				o.(*widget.Label).SetText(text)
+			})
+		listWidget.OnSelected = func(id widget.ListItemID) {
+			showProfile(list[id])
+		}
+
+		c := container.New(layouts.NewVBoxLayout(), listWidget)
+		layouts.SetObjConfigMap(listWidget, &layouts.Size{Height: 400, Width: 200})
+		vBox.Objects = []fyne.CanvasObject{c}
+		vBox.Refresh()
+	}
+	showButton = widget.NewButton("列表", showButtonFunc)
+	return container.NewVBox(container.NewHBox(showButton, addButton), widget.NewSeparator(), vBox)
+}
@@ -0,0 +1,19 @@
+package component
+
+import (
+	"image/color"
+
+	"fyne.io/fyne/v2/theme"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+)
+
+// resultColor 检测结果显示颜色 正常绿色 代理与证书问题橙色 其他失败红色
+func resultColor(r result.Result) color.Color {
+	switch r.Category() {
+	case result.CategoryNone:
+		return color.NRGBA{R: 0x43, G: 0xa0, B: 0x47, A: 0xff}
+	case result.CategoryProxy, result.CategorySecurity:
+		return color.NRGBA{R: 0xfb, G: 0x8c, B: 0x00, A: 0xff}
+	}
+	return theme.ErrorColor()
+}
@@ -0,0 +1,225 @@
+package component
+
+import (
+	"errors"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/layouts"
+	"github.com/flyflyhe/httpMonitorGui/services/alert"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+func silenceScreen(w fyne.Window, silences *alert.SilenceStore, labeler status.Labeler) fyne.CanvasObject {
+	vBox := container.New(layouts.NewVBoxLayout())
+
+	var addButton *widget.Button
+	var showButton *widget.Button
+	var quickButton *widget.Button
+	var showButtonFunc func()
+
+	silenceForm := func(silence alert.Silence, isAdd bool) *widget.Form {
+		nameEntry := widget.NewEntry()
+		var recurrenceNames []string
+		for _, recurrence := range alert.Recurrences {
+			recurrenceNames = append(recurrenceNames, recurrence.String())
+		}
+		recurrenceSelect := widget.NewSelect(recurrenceNames, nil)
+		startEntry := widget.NewEntry()
+		startEntry.SetPlaceHolder(alert.TimeLayout)
+		endEntry := widget.NewEntry()
+		endEntry.SetPlaceHolder(alert.TimeLayout)
+		urlEntry := widget.NewEntry()
+		urlEntry.SetPlaceHolder("为空不限")
+		patternEntry := widget.NewEntry()
+		patternEntry.SetPlaceHolder("eg:https://*.example.com/*")
+		proxyEntry := widget.NewEntry()
+		proxyEntry.SetPlaceHolder("为空不限")
+		commentEntry := widget.NewEntry()
+
+		reset := func() {
+			nameEntry.SetText(silence.Name)
+			recurrenceSelect.SetSelectedIndex(int(silence.Recurrence))
+			startEntry.SetText(silence.Start.Format(alert.TimeLayout))
+			endEntry.SetText(silence.End.Format(alert.TimeLayout))
+			urlEntry.SetText(silence.Url)
+			patternEntry.SetText(silence.Pattern)
+			proxyEntry.SetText(silence.Proxy)
+			commentEntry.SetText(silence.Comment)
+		}
+		reset()
+		if !isAdd {
+			nameEntry.Disable()
+		}
+
+		return &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "名称", Widget: nameEntry},
+				{Text: "重复", Widget: recurrenceSelect},
+				{Text: "开始时间", Widget: startEntry},
+				{Text: "结束时间", Widget: endEntry},
+				{Text: "地址", Widget: urlEntry},
+				{Text: "地址通配", Widget: patternEntry},
+				{Text: "代理", Widget: proxyEntry},
+				{Text: "备注", Widget: commentEntry},
+			},
+			OnCancel:   reset,
+			CancelText: "重置",
+			OnSubmit: func() {
+				if isAdd {
+					if _, ok := silences.Get(nameEntry.Text); ok {
+						dialog.ShowInformation("提示", "维护窗口已存在:"+nameEntry.Text, w)
+						return
+					}
+				}
+				start, err := time.ParseInLocation(alert.TimeLayout, startEntry.Text, time.Local)
+				if err != nil {
+					dialog.ShowError(errors.New("开始时间格式错误 eg:"+alert.TimeLayout), w)
+					return
+				}
+				end, err := time.ParseInLocation(alert.TimeLayout, endEntry.Text, time.Local)
+				if err != nil {
+					dialog.ShowError(errors.New("结束时间格式错误 eg:"+alert.TimeLayout), w)
+					return
+				}
+
+				err = silences.Save(alert.Silence{
+					Name:       nameEntry.Text,
+					Url:        urlEntry.Text,
+					Pattern:    patternEntry.Text,
+					Proxy:      proxyEntry.Text,
+					Start:      start,
+					End:        end,
+					Recurrence: alert.Recurrences[recurrenceSelect.SelectedIndex()],
+					Comment:    commentEntry.Text,
+				})
+				if err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "保存成功", w)
+				}
+			},
+			SubmitText: "保存",
+		}
+	}
+
+	addButton = widget.NewButton("添加窗口", func() {
+		buttonFocusLost(addButton, showButton, quickButton)
+		addButton.FocusGained()
+
+		start := time.Now().Truncate(time.Hour).Add(time.Hour)
+		form := silenceForm(alert.Silence{Start: start, End: start.Add(time.Hour), Recurrence: alert.RecurWeekly}, true)
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form)}
+		vBox.Refresh()
+	})
+
+	//临时静默 从现在开始持续指定时长
+	quickButton = widget.NewButton("临时静默", func() {
+		buttonFocusLost(addButton, showButton, quickButton)
+		quickButton.FocusGained()
+
+		urlEntry := widget.NewEntry()
+		urlEntry.SetPlaceHolder("为空不限")
+		patternEntry := widget.NewEntry()
+		patternEntry.SetPlaceHolder("eg:https://*.example.com/*")
+		proxyEntry := widget.NewEntry()
+		proxyEntry.SetPlaceHolder("为空不限")
+		durationEntry := widget.NewEntry()
+		durationEntry.SetText("1h")
+
+		form := &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "地址", Widget: urlEntry},
+				{Text: "地址通配", Widget: patternEntry},
+				{Text: "代理", Widget: proxyEntry},
+				{Text: "时长", Widget: durationEntry},
+			},
+			OnSubmit: func() {
+				d, err := time.ParseDuration(durationEntry.Text)
+				if err != nil {
+					dialog.ShowError(errors.New("时长格式错误 eg:30m"), w)
+					return
+				}
+				now := time.Now()
+				err = silences.Save(alert.Silence{
+					Name:    "临时静默" + now.Format("0102150405"),
+					Url:     urlEntry.Text,
+					Pattern: patternEntry.Text,
+					Proxy:   proxyEntry.Text,
+					Start:   now,
+					End:     now.Add(d),
+				})
+				if err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "已静默到"+now.Add(d).Format(alert.TimeLayout), w)
+				}
+			},
+			SubmitText: "静默",
+		}
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form)}
+		vBox.Refresh()
+	})
+
+	showSilence := func(silence alert.Silence) {
+		deleteButton := widget.NewButton("删除", func() {
+			dialog.ShowConfirm("操作", "是否删除", func(b bool) {
+				if b {
+					if err := silences.Delete(silence.Name); err != nil {
+						dialog.ShowError(err, w)
+					} else {
+						dialog.ShowInformation("提示", "删除成功", w)
+						showButtonFunc()
+					}
+				}
+			}, w)
+		})
+
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(silenceForm(silence, false), container.NewHBox(deleteButton))}
+		vBox.Refresh()
+	}
+
+	showButtonFunc = func() {
+		buttonFocusLost(addButton, showButton, quickButton)
+		showButton.FocusGained()
+
+		list := silences.List()
+		now := time.Now()
+		listWidget := widget.NewList(
+			func() int {
+				return len(list)
+			},
+			func() fyne.CanvasObject {
+				return widget.NewLabel("template")
+			},
+			func(i widget.ListItemID, o fyne.CanvasObject) {
+				text := list[i].Name + "--" + list[i].Describe(labeler)
+				if list[i].Active(now) {
+					text += "(生效中)"
+				} else if list[i].Expired(now) {
+					text += "(已结束)"
+				}
+				o.(*widget.Label).SetText(text)
+			})
+		listWidget.OnSelected = func(id widget.ListItemID) {
+			showSilence(list[id])
+		}
+		pruneButton := widget.NewButton("清理已结束", func() {
+			if err := silences.Prune(time.Now()); err != nil {
+				dialog.ShowError(err, w)
+			} else {
+				showButtonFunc()
+			}
+		})
+
+		layouts.SetObjConfigMap(listWidget, &layouts.Size{Height: 400, Width: 200})
+		vBox.Objects = []fyne.CanvasObject{container.New(layouts.NewVBoxLayout(), container.NewHBox(pruneButton), listWidget)}
+		vBox.Refresh()
+	}
+	showButton = widget.NewButton("列表", showButtonFunc)
+
+	return container.NewVBox(container.NewHBox(showButton, addButton, quickButton), widget.NewSeparator(), vBox)
+}
@@ -0,0 +1,28 @@
+package component
+
+import (
+	"path/filepath"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"github.com/flyflyhe/httpMonitorGui/services/statuspage"
+)
+
+// ShowStatusPageExport 选择目录后生成一次状态页
+func ShowStatusPageExport(w fyne.Window, generator *statuspage.Generator) {
+	open := dialog.NewFolderOpen(func(dir fyne.ListableURI, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if dir == nil {
+			return
+		}
+		if err = generator.Generate(dir.Path()); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("提示", "状态页已生成 "+filepath.Join(dir.Path(), statuspage.FileName), w)
+	}, w)
+	open.Show()
+}
@@ -0,0 +1,126 @@
+package component
+
+import (
+	"io"
+	"strconv"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/transfer"
+)
+
+// ShowExport 导出地址与代理 格式由文件扩展名决定
+func ShowExport(w fyne.Window, backend rpc.MonitorBackend) {
+	config, err := transfer.Export(backend)
+	if err != nil {
+		dialog.ShowError(err, w)
+		return
+	}
+
+	save := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		format, err := transfer.FormatOf(writer.URI().Path())
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		data, err := transfer.Encode(config, format)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if _, err = writer.Write(data); err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		dialog.ShowInformation("提示", "导出成功 地址"+strconv.Itoa(len(config.Urls))+"个 代理"+strconv.Itoa(len(config.Proxies))+"个", w)
+	}, w)
+	save.SetFileName("httpMonitor.yaml")
+	save.Show()
+}
+
+// ShowImport 导入地址与代理 先预览新增 修改与冲突 确认后写入服务
+func ShowImport(w fyne.Window, backend rpc.MonitorBackend) {
+	open := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		format, err := transfer.FormatOf(reader.URI().Path())
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		config, err := transfer.Decode(data, format)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		plan, err := transfer.NewPlan(backend, config)
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		showImportPlan(w, backend, plan)
+	}, w)
+	open.SetFilter(storage.NewExtensionFileFilter(transfer.Extensions))
+	open.Show()
+}
+
+func showImportPlan(w fyne.Window, backend rpc.MonitorBackend, plan *transfer.Plan) {
+	if len(plan.Items) == 0 {
+		dialog.ShowInformation("提示", "文件中没有地址和代理", w)
+		return
+	}
+
+	list := widget.NewList(
+		func() int {
+			return len(plan.Items)
+		},
+		func() fyne.CanvasObject {
+			return widget.NewLabel("template")
+		},
+		func(i widget.ListItemID, o fyne.CanvasObject) {
+			o.(*widget.Label).SetText(plan.Items[i].String())
+		})
+	content := container.NewBorder(widget.NewLabel(plan.Summary()), nil, nil, nil, list)
+
+	confirm := dialog.NewCustomConfirm("导入预览", "导入", "取消", content, func(b bool) {
+		if !b {
+			return
+		}
+		if plan.Count(transfer.ActionAdd)+plan.Count(transfer.ActionChange) == 0 {
+			dialog.ShowInformation("提示", "没有需要导入的内容", w)
+			return
+		}
+		if err := plan.Apply(backend); err != nil {
+			dialog.ShowError(err, w)
+		} else {
+			dialog.ShowInformation("提示", "导入成功", w)
+		}
+	}, w)
+	confirm.Resize(fyne.NewSize(560, 400))
+	confirm.Show()
+}
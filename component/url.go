@@ -6,9 +6,11 @@ import (
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/flyflyhe/httpMonitorGui/layouts"
+	"github.com/flyflyhe/httpMonitorGui/services/group"
 	"github.com/flyflyhe/httpMonitorGui/services/rpc"
 	"log"
-	"strconv"
+	"sort"
+	"strings"
 )
 
 func buttonFocusLost(buttons ...*widget.Button) {
@@ -20,11 +22,37 @@ func buttonFocusLost(buttons ...*widget.Button) {
 type urlIntervalStruct struct {
 	Url      string
 	Interval int32
+	Meta     group.Meta
 }
 
-func urlScreen(w fyne.Window) fyne.CanvasObject {
+// groupEntries 分组与标签输入框 分组可从已有分组中选择 标签用逗号分隔
+func groupEntries(groups *group.Store, meta group.Meta) (*widget.SelectEntry, *widget.Entry, func()) {
+	groupEntry := widget.NewSelectEntry(groups.Groups())
+	groupEntry.SetPlaceHolder("为空不分组")
+	tagsEntry := widget.NewEntry()
+	tagsEntry.SetPlaceHolder("多个用逗号分隔")
+	reset := func() {
+		groupEntry.SetText(meta.Group)
+		tagsEntry.SetText(strings.Join(meta.Tags, ","))
+	}
+	reset()
+
+	return groupEntry, tagsEntry, reset
+}
+
+// urlScreen filter不为空时只显示该分组或标签下的url
+func urlScreen(w fyne.Window, backend rpc.MonitorBackend, groups *group.Store, filter group.Filter) fyne.CanvasObject {
 	vBox := container.New(layouts.NewVBoxLayout())
 
+	//新增地址默认属于当前分组与标签
+	defaultMeta := group.Meta{Group: filter.Group}
+	if defaultMeta.Group == group.Ungrouped {
+		defaultMeta.Group = ""
+	}
+	if filter.Tag != "" {
+		defaultMeta.Tags = []string{filter.Tag}
+	}
+
 	var addButton *widget.Button
 	var deleteButton *widget.Button
 	var showButton *widget.Button
@@ -34,25 +62,36 @@ func urlScreen(w fyne.Window) fyne.CanvasObject {
 		addButton.FocusGained()
 		urlEntry := widget.NewEntry()
 		intervalEntry := widget.NewEntry()
+		intervalEntry.SetPlaceHolder("毫秒或时长 eg:500 30s")
+		groupEntry, tagsEntry, resetGroup := groupEntries(groups, defaultMeta)
 
 		form := &widget.Form{
 			Items: []*widget.FormItem{ // we can specify items in the constructor
 				{Text: "http地址", Widget: urlEntry},
-				{Text: "间隔时间毫秒", Widget: intervalEntry},
+				{Text: "间隔时间", Widget: intervalEntry},
+				{Text: "分组", Widget: groupEntry},
+				{Text: "标签", Widget: tagsEntry},
 			},
 			OnCancel: func() {
 				urlEntry.SetText("")
 				intervalEntry.SetText("")
+				resetGroup()
 			},
 			CancelText: "重置",
 			OnSubmit: func() { // optional, handle form submission
 				log.Println("Form submitted:", urlEntry.Text, intervalEntry.Text)
-				interval, err := strconv.Atoi(intervalEntry.Text)
+				if err := rpc.ValidateUrl(urlEntry.Text); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				interval, err := rpc.ParseInterval(intervalEntry.Text)
 				if err != nil {
 					dialog.ShowError(err, w)
 					return
 				}
-				if err = rpc.SetUrl(urlEntry.Text, int32(interval)); err != nil {
+				if err = backend.SetUrl(urlEntry.Text, interval); err != nil {
+					dialog.ShowError(err, w)
+				} else if err = groups.Save(group.Meta{Url: urlEntry.Text, Group: groupEntry.Text, Tags: group.ParseTags(tagsEntry.Text)}); err != nil {
 					dialog.ShowError(err, w)
 				} else {
 					dialog.ShowInformation("提示", "保存成功", w)
@@ -81,7 +120,9 @@ func urlScreen(w fyne.Window) fyne.CanvasObject {
 			CancelText: "重置",
 			OnSubmit: func() { // optional, handle form submission
 				log.Println("Form submitted:", urlEntry.Text)
-				if err := rpc.DeleteUrl(urlEntry.Text); err != nil {
+				if err := backend.DeleteUrl(urlEntry.Text); err != nil {
+					dialog.ShowError(err, w)
+				} else if err = groups.Delete(urlEntry.Text); err != nil {
 					dialog.ShowError(err, w)
 				} else {
 					dialog.ShowInformation("提示", "删除成功", w)
@@ -96,19 +137,111 @@ func urlScreen(w fyne.Window) fyne.CanvasObject {
 	})
 
 	var showButtonFunc func()
+
+	//editUrl 修改地址与间隔 间隔从服务端重新读取
+	editUrl := func(url string) {
+		urlIntervalMap, err := backend.ListUrlInterval()
+		if err != nil {
+			dialog.ShowError(err, w)
+			return
+		}
+		current, ok := urlIntervalMap[url]
+		if !ok {
+			dialog.ShowInformation("提示", "地址不存在:"+url, w)
+			showButtonFunc()
+			return
+		}
+
+		urlEntry := widget.NewEntry()
+		intervalEntry := widget.NewEntry()
+		intervalEntry.SetPlaceHolder("毫秒或时长 eg:500 30s")
+		groupEntry, tagsEntry, resetGroup := groupEntries(groups, groups.Get(url))
+		reset := func() {
+			urlEntry.SetText(url)
+			intervalEntry.SetText(rpc.FormatInterval(current))
+			resetGroup()
+		}
+		reset()
+
+		form := &widget.Form{
+			Items: []*widget.FormItem{
+				{Text: "http地址", Widget: urlEntry},
+				{Text: "间隔时间", Widget: intervalEntry},
+				{Text: "分组", Widget: groupEntry},
+				{Text: "标签", Widget: tagsEntry},
+			},
+			OnCancel:   reset,
+			CancelText: "重置",
+			OnSubmit: func() {
+				log.Println("Form submitted:", url, urlEntry.Text, intervalEntry.Text)
+				interval, err := rpc.ParseInterval(intervalEntry.Text)
+				if err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				if err = rpc.UpdateUrl(backend, url, urlEntry.Text, interval); err != nil {
+					dialog.ShowError(err, w)
+					return
+				}
+				if urlEntry.Text != url {
+					if err = groups.Rename(url, urlEntry.Text); err != nil {
+						dialog.ShowError(err, w)
+						return
+					}
+				}
+				if err = groups.Save(group.Meta{Url: urlEntry.Text, Group: groupEntry.Text, Tags: group.ParseTags(tagsEntry.Text)}); err != nil {
+					dialog.ShowError(err, w)
+				} else {
+					dialog.ShowInformation("提示", "保存成功", w)
+					showButtonFunc()
+				}
+			},
+			SubmitText: "保存",
+		}
+		deleteButton := widget.NewButton("删除", func() {
+			dialog.ShowConfirm("操作", "是否删除", func(b bool) {
+				if b {
+					if err := backend.DeleteUrl(url); err != nil {
+						dialog.ShowError(err, w)
+					} else if err = groups.Delete(url); err != nil {
+						dialog.ShowError(err, w)
+					} else {
+						dialog.ShowInformation("提示", "删除成功", w)
+						showButtonFunc()
+					}
+				}
+			}, w)
+		})
+
+		vBox.Objects = []fyne.CanvasObject{container.NewVBox(form, container.NewHBox(deleteButton))}
+		vBox.Refresh()
+	}
+
 	showButtonFunc = func() {
 		buttonFocusLost(addButton, deleteButton, showButton)
 		showButton.FocusGained()
-		if urlIntervalMap, err := rpc.ListUrlInterval(); err != nil {
+		if urlIntervalMap, err := backend.ListUrlInterval(); err != nil {
 			dialog.ShowError(err, w)
 		} else {
-			urls := make([]*urlIntervalStruct, len(urlIntervalMap))
-
-			i := 0
+			index := groups.Index()
+			urls := make([]*urlIntervalStruct, 0, len(urlIntervalMap))
 			for url, interval := range urlIntervalMap {
-				urls[i] = &urlIntervalStruct{Url: url, Interval: interval}
-				i++
+				if filter.MatchUrl(index, url) {
+					meta, ok := index[url]
+					if !ok {
+						meta.Url = url
+					}
+					urls = append(urls, &urlIntervalStruct{Url: url, Interval: interval, Meta: meta})
+				}
 			}
+			//按分组排序 未分组的在最后
+			sort.Slice(urls, func(i, j int) bool {
+				gi, gj := urls[i].Meta.Group, urls[j].Meta.Group
+				if gi != gj {
+					return gj == "" || (gi != "" && gi < gj)
+				}
+				return urls[i].Url < urls[j].Url
+			})
 			list := widget.NewList(
 				func() int {
 					return len(urls)
@@ -117,20 +250,17 @@ func urlScreen(w fyne.Window) fyne.CanvasObject {
 					return widget.NewLabel("template")
 				},
 				func(i widget.ListItemID, o fyne.CanvasObject) {
-					intervalStr := strconv.FormatInt(int64(urls[i].Interval), 10)
-					o.(*widget.Label).SetText(urls[i].Url + "--" + intervalStr + "ms")
+					text := urls[i].Url + "--" + rpc.FormatInterval(urls[i].Interval)
+					if filter.Group == "" && urls[i].Meta.Group != "" {
+						text = "[" + urls[i].Meta.Group + "] " + text
+					}
+					for _, tag := range urls[i].Meta.Tags {
+						text += " #" + tag
+					}
+					o.(*widget.Label).SetText(text)
 				})
 			list.OnSelected = func(id widget.ListItemID) {
-				dialog.ShowConfirm("操作", "是否删除", func(b bool) {
-					if b {
-						if err := rpc.DeleteUrl(urls[id].Url); err != nil {
-							dialog.ShowError(err, w)
-						} else {
-							dialog.ShowInformation("提示", "删除成功", w)
-							showButtonFunc()
-						}
-					}
-				}, w)
+				editUrl(urls[id].Url)
 			}
 
 			c := container.New(layouts.NewVBoxLayout(), list)
@@ -140,5 +270,8 @@ func urlScreen(w fyne.Window) fyne.CanvasObject {
 		}
 	}
 	showButton = widget.NewButton("列表", showButtonFunc)
+	if filter != (group.Filter{}) {
+		showButtonFunc()
+	}
 	return container.NewVBox(container.NewHBox(showButton, addButton, deleteButton), widget.NewSeparator(), vBox)
 }
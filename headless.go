@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/alert"
+	"github.com/flyflyhe/httpMonitorGui/services/notify"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+var headless = flag.Bool("headless", false, "无界面运行 监控结果输出到标准输出或日志文件 收到SIGINT/SIGTERM后退出")
+var resultLog = flag.String("log", "", "无界面运行时监控结果追加写入的文件 默认标准输出")
+
+// headlessStartRetry 内置服务启动需要时间 启动监控失败时重试
+const headlessStartRetry = 10
+
+// lineWriter 结果与告警从不同goroutine写入 按行加锁
+type lineWriter struct {
+	m sync.Mutex
+	w io.Writer
+}
+
+func (writer *lineWriter) Println(fields ...string) {
+	writer.m.Lock()
+	defer writer.m.Unlock()
+	if _, err := fmt.Fprintln(writer.w, strings.Join(fields, "\t")); err != nil {
+		log.Println("write result failed", err)
+	}
+}
+
+// runHeadless 启动全部服务的监控直到收到退出信号
+func runHeadless(monitor *rpc.Aggregator, bus *rpc.EventBus, engine *alert.Engine, silences *alert.SilenceStore, channels *notify.Store, labeler status.Labeler) error {
+	out := io.Writer(os.Stdout)
+	if *resultLog != "" {
+		file, err := os.OpenFile(*resultLog, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		out = file
+	}
+	writer := &lineWriter{w: out}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	results := bus.SubscribeLossless("headless")
+	alerts := bus.SubscribeLossless("alert")
+	go notifyAlerts(engine, silences, channels, alerts, func(change alert.Alert) {
+		writer.Println(time.Now().Format(time.RFC3339), "ALERT", change.Title(), change.Summary())
+	})
+
+	if err := startHeadless(ctx, monitor, bus); err != nil {
+		results.Unsubscribe()
+		alerts.Unsubscribe()
+		return err
+	}
+	log.Println("headless monitor started", monitor.Running())
+
+	go func() {
+		<-ctx.Done()
+		log.Println("headless monitor stopping")
+		if errs := monitor.StopMonitor(); len(errs) > 0 {
+			log.Println("stop monitor failed", errs)
+		}
+		results.Unsubscribe()
+		alerts.Unsubscribe()
+	}()
+
+	for res := range results.C {
+		if res.MonitorResponse == nil || res.Url == "" {
+			continue
+		}
+		for _, row := range resultRows(res, labeler) {
+			text := row.Status
+			if !row.Ok {
+				text += " " + row.Message
+			}
+			writer.Println(row.Time.Format(time.RFC3339), row.Source, row.Url, row.ProxyName, text)
+		}
+	}
+
+	return nil
+}
+
+// startHeadless 启动全部服务 至少一个服务启动成功即返回
+func startHeadless(ctx context.Context, monitor *rpc.Aggregator, bus *rpc.EventBus) error {
+	sources := monitor.Sources()
+	if len(sources) == 0 {
+		return errors.New("没有可用的服务")
+	}
+
+	var errs map[string]error
+	for i := 0; i < headlessStartRetry; i++ {
+		errs = monitor.StartMonitor(bus, sources)
+		if len(monitor.Running()) > 0 {
+			for name, err := range errs {
+				log.Println("start monitor failed", name, err)
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Second):
+		}
+	}
+
+	var msgs []string
+	for name, err := range errs {
+		msgs = append(msgs, name+":"+err.Error())
+	}
+	sort.Strings(msgs)
+	return errors.New("启动监控失败 " + strings.Join(msgs, " "))
+}
@@ -1,14 +1,30 @@
 package main
 
 import (
+	"context"
+	"flag"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"github.com/flyflyhe/httpMonitorGui/component"
+	"github.com/flyflyhe/httpMonitorGui/services/alert"
+	"github.com/flyflyhe/httpMonitorGui/services/api"
 	"github.com/flyflyhe/httpMonitorGui/services/global"
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/history"
+	"github.com/flyflyhe/httpMonitorGui/services/metrics"
+	"github.com/flyflyhe/httpMonitorGui/services/notify"
+	"github.com/flyflyhe/httpMonitorGui/services/probe"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
 	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/statuspage"
 	"github.com/flyflyhe/httpMonitorGui/themes"
 	"log"
+	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
 
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/cmd/fyne_settings/settings"
@@ -21,18 +37,191 @@ const preferenceCurrentTutorial = "currentTutorial"
 
 var topWindow fyne.Window
 
+var memoryBackend = flag.Bool("memory", false, "使用内存后端 不启动httpMonitor服务")
+var localBackend = flag.Bool("local", false, "使用内置检测引擎 不启动httpMonitor服务")
+var rpcTimeout = flag.Duration("timeout", rpc.DefaultCallOptions.Timeout, "grpc调用超时")
+var historyRetention = flag.Duration("history", 30*24*time.Hour, "监控历史原始记录保留时长 状态页使用的每日汇总另外保留")
+var apiAddr = flag.String("api", "", "本地HTTP/JSON接口监听地址 如127.0.0.1:8787 为空不启用")
+var apiToken = flag.String("api-token", os.Getenv("HTTPMONITOR_API_TOKEN"), "HTTP接口的token 默认读取环境变量HTTPMONITOR_API_TOKEN")
+var statusPageDir = flag.String("statuspage", "", "定时生成静态状态页的目录 为空不启用")
+var statusPageInterval = flag.Duration("statuspage-interval", statuspage.DefaultInterval, "状态页生成间隔")
+var statusPageTitle = flag.String("statuspage-title", statuspage.DefaultTitle, "状态页标题")
+var metricsAddr = flag.String("metrics", "", "Prometheus指标监听地址 如127.0.0.1:9464 为空不启用")
+
 func main() {
-	go rpc.Start() //启动服务
+	flag.Parse()
+	//间隔与保留时长为0或负数时定时任务无法运行
+	for _, f := range []struct {
+		name string
+		d    time.Duration
+	}{{"history", *historyRetention}, {"statuspage-interval", *statusPageInterval}} {
+		if f.d <= 0 {
+			fmt.Fprintf(flag.CommandLine.Output(), "-%s必须大于0\n", f.name)
+			flag.Usage()
+			os.Exit(exitUsage)
+		}
+	}
+	rpc.DefaultCallOptions.Timeout = *rpcTimeout
+	isCli := cliCommands[flag.Arg(0)]
 
 	a := app.NewWithID("io.apple.httpMonitorGui")
 	global.TopFyneApp = a
+
+	profiles := rpc.NewProfileStore(a.Preferences())
+	conns := rpc.NewConnManager()
+	var backend rpc.MonitorBackend
+	var sources func() []rpc.MonitorBackend
+	switch {
+	case *memoryBackend:
+		backend = rpc.NewMemoryBackend()
+		sources = func() []rpc.MonitorBackend {
+			return []rpc.MonitorBackend{backend}
+		}
+	case *localBackend:
+		backend = probe.NewEngine(a.Preferences())
+		sources = func() []rpc.MonitorBackend {
+			return []rpc.MonitorBackend{backend}
+		}
+	default:
+		//命令行管理已运行的服务 不再启动
+		if !isCli {
+			go rpc.Start() //启动服务
+		}
+		grpcBackend := rpc.NewGrpcBackend(conns, profiles.Active())
+		profiles.SetOnActiveChanged(grpcBackend.SetProfile)
+		profiles.SetOnDeleted(func(name string) {
+			if err := conns.Remove(name); err != nil {
+				log.Println("close grpc conn failed", name, err)
+			}
+		})
+		backend = grpcBackend
+		sources = grpcSources(conns, profiles)
+	}
+	proxyLabels := proxy.NewLabelStore(a.Preferences())
+	//在打开历史记录前执行 界面运行时历史记录文件被锁定
+	if isCli {
+		code := runCli(&cli{
+			backend: backend,
+			monitor: rpc.NewAggregator(sources),
+			bus:     rpc.NewEventBus(),
+			labels:  proxyLabels,
+			out:     os.Stdout,
+			errOut:  os.Stderr,
+		}, flag.Args())
+		if err := conns.Close(); err != nil {
+			log.Println("close grpc conn failed", err)
+		}
+		os.Exit(code)
+	}
+	store, err := history.Open(filepath.Join(a.Storage().RootURI().Path(), "history.db"))
+	if err != nil {
+		log.Fatalln("open history failed", err)
+	}
+	bus := rpc.GetEventBus()
+	//关闭前等待已收到的结果写完
+	historySub := bus.SubscribeLossless("history")
+	historySaved := make(chan struct{})
+	go func() {
+		store.Save(historySub)
+		close(historySaved)
+	}()
+	rules := alert.NewRuleStore(a.Preferences())
+	alerts := alert.NewEngine(rules.List(), proxyLabels.Name)
+	rules.SetOnChanged(alerts.SetRules)
+	channels := notify.NewStore(a.Preferences())
+	silences := alert.NewSilenceStore(a.Preferences())
+	monitor := rpc.NewAggregator(sources)
+	var metricsServer *http.Server
+	if *metricsAddr != "" {
+		collector := metrics.NewCollector(proxyLabels.Name)
+		go collector.Run(bus.Subscribe("metrics", 1000))
+		if metricsServer, err = metrics.Serve(*metricsAddr, collector); err != nil {
+			log.Fatalln("start metrics failed", err)
+		}
+		log.Println("metrics listening on", *metricsAddr)
+	}
+	var apiServer *http.Server
+	var apiHandler *api.Server
+	if *apiAddr != "" {
+		if apiHandler, err = api.NewServer(backend, monitor, bus, proxyLabels, *apiToken); err != nil {
+			log.Fatalln("start api failed", err)
+		}
+		apiHandler.ProbeUrl = proxy.ProbeUrl(a.Preferences())
+		if apiServer, err = api.Serve(*apiAddr, apiHandler); err != nil {
+			log.Fatalln("start api failed", err)
+		}
+		log.Println("api listening on", *apiAddr)
+	}
+	groups := group.NewStore(a.Preferences())
+	statusPage := &statuspage.Generator{
+		Store:   store,
+		Urls:    backend.ListUrl,
+		Groups:  groups,
+		Options: statuspage.Options{Title: *statusPageTitle},
+	}
+	//定时任务 退出时取消
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	go store.RunPrune(jobsCtx, *historyRetention, time.Hour)
+	if *statusPageDir != "" {
+		go statusPage.Run(jobsCtx, *statusPageDir, *statusPageInterval)
+		log.Println("status page written to", *statusPageDir, "every", *statusPageInterval)
+	}
+	shutdown := func() {
+		stopJobs()
+		for _, server := range []*http.Server{metricsServer, apiServer} {
+			if server == nil {
+				continue
+			}
+			if err := server.Close(); err != nil {
+				log.Println("close http server failed", err)
+			}
+		}
+		if apiHandler != nil {
+			apiHandler.Close()
+		}
+		if err := conns.Close(); err != nil {
+			log.Println("close grpc conn failed", err)
+		}
+		historySub.Unsubscribe()
+		<-historySaved
+		if err := store.Close(); err != nil {
+			log.Println("close history failed", err)
+		}
+	}
+
+	if *headless {
+		err := runHeadless(monitor, bus, alerts, silences, channels, proxyLabels.Name)
+		shutdown()
+		if err != nil {
+			log.Fatalln("headless monitor failed", err)
+		}
+		return
+	}
+
+	go notifyAlerts(alerts, silences, channels, bus.SubscribeLossless("alert"), func(change alert.Alert) {
+		a.SendNotification(fyne.NewNotification(change.Title(), change.Summary()))
+	})
+	component.InitAppViews(&component.Services{
+		Backend:     backend,
+		Profiles:    profiles,
+		Conns:       conns,
+		Monitor:     monitor,
+		Bus:         bus,
+		History:     store,
+		Rules:       rules,
+		Alerts:      alerts,
+		Silences:    silences,
+		Channels:    channels,
+		Groups:      groups,
+		ProxyLabels: proxyLabels,
+	})
 	a.SetIcon(theme.FyneLogo())
 	logLifecycle(a)
 	w := a.NewWindow("url监控")
 	topWindow = w
 
 	a.Settings().SetTheme(&themes.CTheme{})
-	w.SetMainMenu(makeMenu(a, w))
+	w.SetMainMenu(makeMenu(a, w, backend, statusPage))
 	w.SetMaster()
 
 	content := container.NewMax()
@@ -40,12 +229,14 @@ func main() {
 	intro := widget.NewLabel("An introduction would probably go\nhere, as well as a")
 	intro.Wrapping = fyne.TextWrapWord
 	setComponent := func(t component.AppView) {
+		component.CloseView()
 		if fyne.CurrentDevice().IsMobile() {
 			child := a.NewWindow(t.Title)
 			topWindow = child
 			child.SetContent(t.View(topWindow))
 			child.Show()
 			child.SetOnClosed(func() {
+				component.CloseView()
 				topWindow = w
 			})
 			return
@@ -72,6 +263,65 @@ func main() {
 	w.Resize(fyne.NewSize(640, 460))
 	w.FixedSize()
 	w.ShowAndRun()
+	shutdown()
+}
+
+// grpcSources 每个连接配置对应一个服务 同名配置复用同一个GrpcBackend
+func grpcSources(conns *rpc.ConnManager, profiles *rpc.ProfileStore) func() []rpc.MonitorBackend {
+	var m sync.Mutex
+	backends := make(map[string]*rpc.GrpcBackend)
+
+	return func() []rpc.MonitorBackend {
+		m.Lock()
+		defer m.Unlock()
+
+		var sources []rpc.MonitorBackend
+		listed := make(map[string]bool)
+		for _, profile := range profiles.List() {
+			listed[profile.Name] = true
+			backend, ok := backends[profile.Name]
+			if !ok {
+				backend = rpc.NewGrpcBackend(conns, profile)
+				backends[profile.Name] = backend
+			} else if backend.Profile() != profile {
+				backend.SetProfile(profile)
+			}
+			sources = append(sources, backend)
+		}
+		//已删除的配置不再保留
+		for name := range backends {
+			if !listed[name] {
+				delete(backends, name)
+			}
+		}
+
+		return sources
+	}
+}
+
+// notifyAlerts 告警触发 抖动和恢复时调用local(桌面通知或无界面时输出) 并发送到启用的通知渠道 维护窗口内不通知
+func notifyAlerts(engine *alert.Engine, silences *alert.SilenceStore, channels *notify.Store, sub *rpc.Subscription, local func(alert.Alert)) {
+	for res := range sub.C {
+		for _, change := range engine.Evaluate(res) {
+			if silence, ok := silences.Silenced(change, time.Now()); ok {
+				log.Println("alert silenced by", silence.Name, change.Title())
+				continue
+			}
+			local(change)
+
+			go func(change alert.Alert) {
+				ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+				defer cancel()
+				channels.Send(ctx, notify.Message{
+					Title: change.Title(),
+					Text:  change.Summary(),
+					Url:   change.Url,
+					State: change.State.String(),
+					Time:  time.Now(),
+				})
+			}(change)
+		}
+	}
 }
 
 func logLifecycle(a fyne.App) {
@@ -89,7 +339,7 @@ func logLifecycle(a fyne.App) {
 	})
 }
 
-func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
+func makeMenu(a fyne.App, w fyne.Window, backend rpc.MonitorBackend, statusPage *statuspage.Generator) *fyne.MainMenu {
 	newItem := fyne.NewMenuItem("New", nil)
 	checkedItem := fyne.NewMenuItem("Checked", nil)
 	checkedItem.Checked = true
@@ -105,6 +355,15 @@ func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
 		fyne.NewMenuItem("Directory", func() { fmt.Println("Menu New->Directory") }),
 		otherItem,
 	)
+	importItem := fyne.NewMenuItem("导入配置", func() {
+		component.ShowImport(w, backend)
+	})
+	exportItem := fyne.NewMenuItem("导出配置", func() {
+		component.ShowExport(w, backend)
+	})
+	statusPageItem := fyne.NewMenuItem("导出状态页", func() {
+		component.ShowStatusPageExport(w, statusPage)
+	})
 	settingsItem := fyne.NewMenuItem("Settings", func() {
 		w := a.NewWindow("Fyne Settings")
 		w.SetContent(settings.NewSettings().LoadAppearanceScreen(w))
@@ -145,7 +404,7 @@ func makeMenu(a fyne.App, w fyne.Window) *fyne.MainMenu {
 		}))
 
 	// a quit item will be appended to our first (File) menu
-	file := fyne.NewMenu("File", newItem, checkedItem, disabledItem)
+	file := fyne.NewMenu("File", importItem, exportItem, statusPageItem, fyne.NewMenuItemSeparator(), newItem, checkedItem, disabledItem)
 	if !fyne.CurrentDevice().IsMobile() {
 		file.Items = append(file.Items, fyne.NewMenuItemSeparator(), settingsItem)
 	}
@@ -188,6 +447,8 @@ func makeNav(setComponent func(com component.AppView), loadPrevious bool) fyne.C
 		},
 	}
 
+	component.SetOnAppViewsChanged(tree.Refresh)
+
 	if loadPrevious {
 		currentPref := a.Preferences().StringWithFallback(preferenceCurrentTutorial, "welcome")
 		tree.Select(currentPref)
@@ -0,0 +1,427 @@
+package alert
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+type AlertState int
+
+const (
+	AlertFiring AlertState = iota
+	AlertResolved
+	AlertFlapping
+)
+
+func (state AlertState) String() string {
+	switch state {
+	case AlertFiring:
+		return "告警中"
+	case AlertFlapping:
+		return "状态抖动"
+	}
+	return "已恢复"
+}
+
+// Alert 某个url上的告警 Column为空表示针对整个url
+// 同一url与代理触发的多条规则合并为一个告警 Rule为逗号分隔的规则名
+type Alert struct {
+	Rule   string
+	Url    string
+	Column status.Column
+	// ColumnTitle Column的显示名称 如"[local]直连"
+	ColumnTitle string
+	State       AlertState
+	Since       time.Time
+	Message     string
+	// ResolvedAt 仅AlertResolved有效
+	ResolvedAt time.Time
+}
+
+// Duration 告警持续时间 未恢复时计算到now
+func (alert Alert) Duration(now time.Time) time.Duration {
+	if alert.State == AlertResolved {
+		return alert.ResolvedAt.Sub(alert.Since)
+	}
+	return now.Sub(alert.Since)
+}
+
+// Title 通知标题 如"https://www.google.com [local]直连 告警中"
+func (alert Alert) Title() string {
+	title := alert.Url
+	if alert.Column != (status.Column{}) {
+		title += " " + alert.ColumnTitle
+	}
+	return title + " " + alert.State.String()
+}
+
+// Summary 通知内容 恢复时给出故障持续时间
+func (alert Alert) Summary() string {
+	switch alert.State {
+	case AlertResolved:
+		return "[" + alert.Rule + "]恢复 故障持续" + HumanDuration(alert.Duration(alert.ResolvedAt))
+	case AlertFlapping:
+		return "[" + alert.Rule + "]状态反复变化 稳定前不再通知 " + alert.Message
+	}
+	return "[" + alert.Rule + "]" + alert.Message
+}
+
+// HumanDuration 如"30秒" "3分钟" "1小时5分钟"
+func HumanDuration(d time.Duration) string {
+	switch {
+	case d < time.Minute:
+		return strconv.Itoa(int(d/time.Second)) + "秒"
+	case d < time.Hour:
+		return strconv.Itoa(int(d/time.Minute)) + "分钟"
+	}
+	text := strconv.Itoa(int(d/time.Hour)) + "小时"
+	if minutes := int(d % time.Hour / time.Minute); minutes > 0 {
+		text += strconv.Itoa(minutes) + "分钟"
+	}
+	return text
+}
+
+// Flap 抖动检测 Window内状态变化次数达到Transitions视为抖动
+// 抖动期间不再通知 Window内没有变化后按当前状态通知一次
+type Flap struct {
+	Window      time.Duration
+	Transitions int
+}
+
+var DefaultFlap = Flap{Window: 10 * time.Minute, Transitions: 4}
+
+// target url在某个服务的某个代理上的连续失败情况
+type target struct {
+	failures int
+	since    time.Time
+	last     result.Result
+	// seen 最近一次收到结果的时间 interval为最近两次结果的间隔
+	seen     time.Time
+	interval time.Duration
+}
+
+const (
+	// staleChecks 超过这么多个检测间隔没有收到结果的url或代理不再参与告警
+	staleChecks = 3
+	// staleAfter 只收到过一次结果 不知道检测间隔时使用
+	staleAfter = 10 * time.Minute
+)
+
+// stale url或代理已经被删除 或对应的服务已停止
+func (tg *target) stale(now time.Time) bool {
+	if tg.interval > 0 {
+		return now.Sub(tg.seen) > staleChecks*tg.interval
+	}
+	return now.Sub(tg.seen) > staleAfter
+}
+
+type alertKey struct {
+	url    string
+	column status.Column
+}
+
+// firingRules 一个url与代理本次触发的规则 与rules顺序相同
+type firingRules struct {
+	names    []string
+	messages []string
+}
+
+// tracker 一个告警的状态 恢复后保留到变化记录过期 用于抖动检测
+// rules为最近一次触发的规则
+type tracker struct {
+	firing      bool
+	flapping    bool
+	since       time.Time
+	rules       []string
+	message     string
+	transitions []time.Time
+}
+
+func (engine *Engine) alert(key alertKey, tr *tracker) Alert {
+	return Alert{Rule: strings.Join(tr.rules, ","), Url: key.url, Column: key.column, ColumnTitle: engine.labeler.Title(key.column, true), Since: tr.since, Message: tr.message}
+}
+
+// Engine 根据监控结果计算每个url及代理的告警状态 同一url与代理只保留一个告警
+type Engine struct {
+	Flap Flap
+
+	labeler  status.Labeler
+	m        sync.Mutex
+	rules    []Rule
+	targets  map[string]map[status.Column]*target
+	trackers map[alertKey]*tracker
+}
+
+// NewEngine labeler用于告警内容中的代理名称 可以为nil
+func NewEngine(rules []Rule, labeler status.Labeler) *Engine {
+	return &Engine{
+		Flap:     DefaultFlap,
+		labeler:  labeler,
+		rules:    rules,
+		targets:  make(map[string]map[status.Column]*target),
+		trackers: make(map[alertKey]*tracker),
+	}
+}
+
+// SetRules 替换规则 只由已删除规则触发的告警直接丢弃
+func (engine *Engine) SetRules(rules []Rule) {
+	engine.m.Lock()
+	defer engine.m.Unlock()
+
+	engine.rules = rules
+	names := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		names[rule.Name] = true
+	}
+	for key, tr := range engine.trackers {
+		var kept []string
+		for _, name := range tr.rules {
+			if names[name] {
+				kept = append(kept, name)
+			}
+		}
+		if len(kept) == 0 {
+			delete(engine.trackers, key)
+		}
+		tr.rules = kept
+	}
+}
+
+// Evaluate 写入一次监控结果 返回需要通知的告警变化
+func (engine *Engine) Evaluate(res *rpc.MonitorResult) (changes []Alert) {
+	if res == nil || res.MonitorResponse == nil || res.Url == "" {
+		return nil
+	}
+	t := res.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	engine.m.Lock()
+	defer engine.m.Unlock()
+
+	targets, ok := engine.targets[res.Url]
+	if !ok {
+		targets = make(map[status.Column]*target)
+		engine.targets[res.Url] = targets
+	}
+	for proxy, v := range res.Result {
+		column := status.Column{Source: res.Source, Proxy: proxy}
+		tg, ok := targets[column]
+		if !ok {
+			tg = &target{}
+			targets[column] = tg
+		}
+		if !tg.seen.IsZero() && t.After(tg.seen) {
+			tg.interval = t.Sub(tg.seen)
+		}
+		tg.seen = t
+		tg.last = result.Parse(v)
+		if tg.last.OK() {
+			tg.failures = 0
+			tg.since = time.Time{}
+		} else {
+			if tg.failures == 0 {
+				tg.since = t
+			}
+			tg.failures++
+		}
+	}
+	//本次结果中没有的代理已被删除
+	for column := range targets {
+		if _, ok := res.Result[column.Proxy]; !ok && column.Source == res.Source {
+			delete(targets, column)
+		}
+	}
+	engine.prune(t)
+
+	//同一url与代理触发的多条规则合并为一个告警
+	firing := make(map[status.Column]*firingRules)
+	for _, rule := range engine.rules {
+		for column, message := range rule.evaluate(targets, t, engine.labeler) {
+			f, ok := firing[column]
+			if !ok {
+				f = &firingRules{}
+				firing[column] = f
+			}
+			f.names = append(f.names, rule.Name)
+			f.messages = append(f.messages, message)
+		}
+	}
+
+	//本次触发的和之前已有的告警都需要更新
+	var keys []alertKey
+	for column := range firing {
+		keys = append(keys, alertKey{url: res.Url, column: column})
+	}
+	for key := range engine.trackers {
+		if _, ok := firing[key.column]; !ok && key.url == res.Url {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].column.Source != keys[j].column.Source {
+			return keys[i].column.Source < keys[j].column.Source
+		}
+		return keys[i].column.Proxy < keys[j].column.Proxy
+	})
+
+	for _, key := range keys {
+		if alert, ok := engine.update(key, firing[key.column], t); ok {
+			changes = append(changes, alert)
+		}
+	}
+
+	return
+}
+
+// prune 丢弃长时间没有结果的url与代理及其告警 不再通知恢复
+func (engine *Engine) prune(now time.Time) {
+	for url, targets := range engine.targets {
+		for column, tg := range targets {
+			if tg.stale(now) {
+				delete(targets, column)
+			}
+		}
+		if len(targets) == 0 {
+			delete(engine.targets, url)
+		}
+	}
+	for key := range engine.trackers {
+		targets, ok := engine.targets[key.url]
+		if !ok {
+			delete(engine.trackers, key)
+		} else if _, ok = targets[key.column]; !ok && key.column != (status.Column{}) {
+			delete(engine.trackers, key)
+		}
+	}
+}
+
+// update 更新一个告警 返回是否需要通知
+func (engine *Engine) update(key alertKey, rules *firingRules, t time.Time) (Alert, bool) {
+	firing := rules != nil
+	tr, ok := engine.trackers[key]
+	if !ok {
+		if !firing {
+			return Alert{}, false
+		}
+		tr = &tracker{}
+		engine.trackers[key] = tr
+	}
+	if firing {
+		tr.rules = rules.names
+		tr.message = strings.Join(rules.messages, "；")
+	}
+
+	changed := firing != tr.firing
+	if changed {
+		tr.firing = firing
+		tr.transitions = append(tr.transitions, t)
+		if firing && !tr.flapping {
+			tr.since = t
+		}
+	}
+	for len(tr.transitions) > 0 && t.Sub(tr.transitions[0]) > engine.Flap.Window {
+		tr.transitions = tr.transitions[1:]
+	}
+
+	alert := engine.alert(key, tr)
+	switch {
+	case tr.flapping && len(tr.transitions) == 0:
+		//抖动结束 按当前状态通知一次
+		tr.flapping = false
+		if tr.firing {
+			alert.State = AlertFiring
+			return alert, true
+		}
+		delete(engine.trackers, key)
+		alert.State = AlertResolved
+		alert.ResolvedAt = t
+		return alert, true
+	case tr.flapping:
+		return alert, false
+	case changed && engine.Flap.Transitions > 0 && len(tr.transitions) >= engine.Flap.Transitions:
+		tr.flapping = true
+		alert.State = AlertFlapping
+		return alert, true
+	case changed && firing:
+		alert.State = AlertFiring
+		return alert, true
+	case changed:
+		alert.State = AlertResolved
+		alert.ResolvedAt = t
+		return alert, true
+	}
+
+	if !tr.firing && len(tr.transitions) == 0 {
+		delete(engine.trackers, key)
+	}
+
+	return alert, false
+}
+
+// Alerts 正在告警或抖动的列表 按开始时间排序
+func (engine *Engine) Alerts() []Alert {
+	engine.m.Lock()
+	defer engine.m.Unlock()
+
+	var alerts []Alert
+	for key, tr := range engine.trackers {
+		if !tr.firing && !tr.flapping {
+			continue
+		}
+		alert := engine.alert(key, tr)
+		alert.State = AlertFiring
+		if tr.flapping {
+			alert.State = AlertFlapping
+		}
+		alerts = append(alerts, alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool {
+		return alerts[i].Since.Before(alerts[j].Since)
+	})
+
+	return alerts
+}
+
+// evaluate 返回触发的告警及描述 连续失败与持续失败按代理分别告警 多代理失败针对整个url
+func (rule Rule) evaluate(targets map[status.Column]*target, now time.Time, labeler status.Labeler) map[status.Column]string {
+	firing := make(map[status.Column]string)
+	var failing []string
+	for column, tg := range targets {
+		if tg.failures == 0 {
+			continue
+		}
+		failing = append(failing, labeler.Title(column, true)+":"+tg.last.String())
+		switch rule.Kind {
+		case RuleConsecutive:
+			if tg.failures >= rule.Count {
+				firing[column] = rule.Describe() + " " + tg.last.String() + " " + tg.last.Message
+			}
+		case RuleDuration:
+			if now.Sub(tg.since) >= rule.Duration {
+				firing[column] = rule.Describe() + " " + tg.last.String() + " " + tg.last.Message
+			}
+		}
+	}
+
+	if rule.Kind == RuleQuorum {
+		matched := len(failing) >= rule.Count
+		if rule.Of > 0 {
+			matched = len(failing)*rule.Of >= rule.Count*len(targets)
+		}
+		if matched {
+			sort.Strings(failing)
+			firing[status.Column{}] = rule.Describe() + " " + strings.Join(failing, " ")
+		}
+	}
+
+	return firing
+}
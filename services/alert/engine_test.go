@@ -0,0 +1,161 @@
+package alert
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	testUrl   = "https://www.google.com"
+	testProxy = "socks5://127.0.0.1:8000"
+	timeout   = "dial tcp: i/o timeout"
+)
+
+func check(t time.Time, direct, proxy string) *rpc.MonitorResult {
+	return &rpc.MonitorResult{Source: "local", Time: t, MonitorResponse: &httpMonitorRpc.MonitorResponse{
+		Url:    testUrl,
+		Result: map[string]string{"": direct, testProxy: proxy},
+	}}
+}
+
+func TestEngine(t *testing.T) {
+	engine := NewEngine([]Rule{
+		{Name: "consecutive", Kind: RuleConsecutive, Count: 3},
+		{Name: "quorum", Kind: RuleQuorum, Count: 2, Of: 2},
+		{Name: "duration", Kind: RuleDuration, Duration: time.Minute},
+	}, func(proxy string) string {
+		return "东京"
+	})
+	start := time.Now()
+
+	assert.Empty(t, engine.Evaluate(check(start, timeout, "success")))
+	assert.Empty(t, engine.Evaluate(check(start.Add(10*time.Second), timeout, "success")))
+
+	changes := engine.Evaluate(check(start.Add(20*time.Second), timeout, "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "consecutive", changes[0].Rule)
+	assert.Equal(t, AlertFiring, changes[0].State)
+	assert.Equal(t, status.Column{Source: "local"}, changes[0].Column)
+	assert.Contains(t, changes[0].Message, "超时")
+
+	changes = engine.Evaluate(check(start.Add(30*time.Second), timeout, "502 Bad Gateway"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "quorum", changes[0].Rule)
+	assert.Equal(t, status.Column{}, changes[0].Column)
+	assert.Contains(t, changes[0].Message, "[local]东京:HTTP 502")
+
+	//持续时间从第一次失败开始计算 同一代理上的第二条规则合并到已有告警 不再通知
+	changes = engine.Evaluate(check(start.Add(time.Minute), timeout, "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, "quorum", changes[0].Rule)
+	assert.Equal(t, AlertResolved, changes[0].State)
+	alerts := engine.Alerts()
+	assert.Len(t, alerts, 1)
+	assert.Equal(t, "consecutive,duration", alerts[0].Rule)
+	assert.Contains(t, alerts[0].Message, "；")
+
+	changes = engine.Evaluate(check(start.Add(70*time.Second), "success", "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, AlertResolved, changes[0].State)
+	assert.Equal(t, 50*time.Second, changes[0].Duration(time.Now()))
+	assert.Equal(t, "[consecutive,duration]恢复 故障持续50秒", changes[0].Summary())
+	assert.Empty(t, engine.Alerts())
+
+	//删除规则后丢弃对应告警
+	engine.Evaluate(check(start.Add(80*time.Second), timeout, timeout))
+	assert.Len(t, engine.Alerts(), 1)
+	engine.SetRules(nil)
+	assert.Empty(t, engine.Alerts())
+}
+
+func TestEngineFlapping(t *testing.T) {
+	engine := NewEngine([]Rule{{Name: "fail", Kind: RuleConsecutive, Count: 1}}, nil)
+	engine.Flap = Flap{Window: time.Minute, Transitions: 3}
+	start := time.Now()
+
+	//同一url与代理只通知一次
+	changes := engine.Evaluate(check(start, timeout, "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, AlertFiring, changes[0].State)
+	assert.Empty(t, engine.Evaluate(check(start.Add(time.Second), timeout, "success")))
+
+	changes = engine.Evaluate(check(start.Add(2*time.Second), "success", "success"))
+	assert.Equal(t, AlertResolved, changes[0].State)
+
+	//第三次变化进入抖动 之后的变化不再通知
+	changes = engine.Evaluate(check(start.Add(3*time.Second), timeout, "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, AlertFlapping, changes[0].State)
+	assert.Empty(t, engine.Evaluate(check(start.Add(4*time.Second), "success", "success")))
+	assert.Empty(t, engine.Evaluate(check(start.Add(5*time.Second), timeout, "success")))
+	assert.Equal(t, AlertFlapping, engine.Alerts()[0].State)
+
+	//窗口内没有变化后按当前状态通知
+	changes = engine.Evaluate(check(start.Add(2*time.Minute), timeout, "success"))
+	assert.Len(t, changes, 1)
+	assert.Equal(t, AlertFiring, changes[0].State)
+	assert.True(t, changes[0].Since.Equal(start.Add(3*time.Second)))
+
+	changes = engine.Evaluate(check(start.Add(5*time.Minute), "success", "success"))
+	assert.Equal(t, AlertResolved, changes[0].State)
+	assert.Equal(t, "[fail]恢复 故障持续4分钟", changes[0].Summary())
+	assert.Equal(t, "https://www.google.com [local]直连 已恢复", changes[0].Title())
+}
+
+func TestEnginePrune(t *testing.T) {
+	engine := NewEngine([]Rule{{Name: "fail", Kind: RuleConsecutive, Count: 1}}, nil)
+	start := time.Now()
+
+	assert.Len(t, engine.Evaluate(check(start, timeout, timeout)), 2)
+	assert.Len(t, engine.Alerts(), 2)
+
+	//删除代理后对应告警直接丢弃
+	removed := check(start.Add(10*time.Second), timeout, "")
+	delete(removed.Result, testProxy)
+	assert.Empty(t, engine.Evaluate(removed))
+	assert.Len(t, engine.Alerts(), 1)
+
+	//超过3个检测间隔没有结果的url不再告警
+	other := check(start.Add(20*time.Second), "success", "success")
+	other.Url = "https://www.baidu.com"
+	engine.Evaluate(other)
+	assert.Len(t, engine.Alerts(), 1)
+	other.Time = start.Add(time.Minute)
+	engine.Evaluate(other)
+	assert.Empty(t, engine.Alerts())
+}
+
+func TestHumanDuration(t *testing.T) {
+	assert.Equal(t, "30秒", HumanDuration(30*time.Second))
+	assert.Equal(t, "3分钟", HumanDuration(3*time.Minute+10*time.Second))
+	assert.Equal(t, "2小时", HumanDuration(2*time.Hour))
+	assert.Equal(t, "1小时5分钟", HumanDuration(65*time.Minute))
+}
+
+func TestRuleStore(t *testing.T) {
+	store := NewRuleStore(test.NewApp().Preferences())
+	assert.Equal(t, DefaultRules, store.List())
+
+	var changed []Rule
+	store.SetOnChanged(func(rules []Rule) {
+		changed = rules
+	})
+	assert.NotNil(t, store.Save(Rule{Name: "bad", Kind: RuleQuorum, Count: 3, Of: 2}))
+	assert.Nil(t, store.Save(Rule{Name: "slow", Kind: RuleDuration, Duration: 5 * time.Minute}))
+	assert.Len(t, changed, 2)
+	rule, ok := store.Get("slow")
+	assert.True(t, ok)
+	assert.Equal(t, "持续失败超过5m0s", rule.Describe())
+
+	//全部删除后不再回退到默认规则
+	assert.Nil(t, store.Delete(DefaultRules[0].Name))
+	assert.Nil(t, store.Delete("slow"))
+	assert.Empty(t, store.List())
+	assert.Empty(t, changed)
+}
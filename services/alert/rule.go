@@ -0,0 +1,214 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	preferenceRules      = "alertRules"
+	preferenceRulesSaved = "alertRulesSaved"
+)
+
+type RuleKind int
+
+const (
+	// RuleConsecutive 任一代理连续失败Count次
+	RuleConsecutive RuleKind = iota
+	// RuleQuorum 失败代理数达到Count 或失败比例达到Count/Of
+	RuleQuorum
+	// RuleDuration 任一代理持续失败超过Duration
+	RuleDuration
+)
+
+var RuleKinds = []RuleKind{RuleConsecutive, RuleQuorum, RuleDuration}
+
+func (kind RuleKind) String() string {
+	switch kind {
+	case RuleConsecutive:
+		return "连续失败"
+	case RuleQuorum:
+		return "多代理失败"
+	case RuleDuration:
+		return "持续失败"
+	}
+	return "未知"
+}
+
+// Rule 告警规则 对每个url单独计算
+type Rule struct {
+	Name string
+	Kind RuleKind
+	// Count 连续失败次数N 或失败代理数K
+	Count int
+	// Of 代理总数M 为0时只比较失败代理数
+	Of int
+	// Duration 持续失败时长D
+	Duration time.Duration
+}
+
+// DefaultRules 未配置规则时使用
+var DefaultRules = []Rule{{Name: "连续失败3次", Kind: RuleConsecutive, Count: 3}}
+
+func (rule Rule) Validate() error {
+	if rule.Name == "" {
+		return errors.New("名称不能为空")
+	}
+	switch rule.Kind {
+	case RuleConsecutive:
+		if rule.Count < 1 {
+			return errors.New("失败次数必须大于0")
+		}
+	case RuleQuorum:
+		if rule.Count < 1 {
+			return errors.New("失败代理数必须大于0")
+		}
+		if rule.Of != 0 && rule.Of < rule.Count {
+			return errors.New("代理总数不能小于失败代理数")
+		}
+	case RuleDuration:
+		if rule.Duration <= 0 {
+			return errors.New("持续时间必须大于0")
+		}
+	default:
+		return errors.New("未知规则类型")
+	}
+
+	return nil
+}
+
+// Describe 规则条件描述
+func (rule Rule) Describe() string {
+	switch rule.Kind {
+	case RuleConsecutive:
+		return fmt.Sprintf("连续失败%d次", rule.Count)
+	case RuleQuorum:
+		if rule.Of > 0 {
+			return fmt.Sprintf("%d/%d的代理失败", rule.Count, rule.Of)
+		}
+		return fmt.Sprintf("至少%d个代理失败", rule.Count)
+	case RuleDuration:
+		return "持续失败超过" + rule.Duration.String()
+	}
+	return rule.Kind.String()
+}
+
+// RuleStore 告警规则 保存在fyne Preferences中
+type RuleStore struct {
+	prefs     fyne.Preferences
+	m         sync.Mutex
+	onChanged func([]Rule)
+}
+
+func NewRuleStore(prefs fyne.Preferences) *RuleStore {
+	return &RuleStore{prefs: prefs}
+}
+
+// SetOnChanged 规则变化时回调
+func (store *RuleStore) SetOnChanged(f func([]Rule)) {
+	store.m.Lock()
+	defer store.m.Unlock()
+	store.onChanged = f
+}
+
+// List 返回全部规则 未保存过规则时返回DefaultRules
+func (store *RuleStore) List() []Rule {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.list()
+}
+
+func (store *RuleStore) Get(name string) (Rule, bool) {
+	for _, rule := range store.List() {
+		if rule.Name == name {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
+
+// Save 新增或按名称覆盖规则
+func (store *RuleStore) Save(rule Rule) error {
+	if err := rule.Validate(); err != nil {
+		return err
+	}
+
+	store.m.Lock()
+	rules := store.list()
+	replaced := false
+	for i, r := range rules {
+		if r.Name == rule.Name {
+			rules[i] = rule
+			replaced = true
+		}
+	}
+	if !replaced {
+		rules = append(rules, rule)
+	}
+	err := store.save(rules)
+	onChanged := store.onChanged
+	store.m.Unlock()
+
+	if err == nil && onChanged != nil {
+		onChanged(rules)
+	}
+
+	return err
+}
+
+func (store *RuleStore) Delete(name string) error {
+	store.m.Lock()
+	rules := store.list()
+	var err error
+	deleted := false
+	for i, r := range rules {
+		if r.Name == name {
+			rules = append(rules[:i], rules[i+1:]...)
+			err = store.save(rules)
+			deleted = true
+			break
+		}
+	}
+	onChanged := store.onChanged
+	store.m.Unlock()
+
+	if deleted && err == nil && onChanged != nil {
+		onChanged(rules)
+	}
+
+	return err
+}
+
+// list 保存过规则后即使为空也不再使用DefaultRules
+func (store *RuleStore) list() []Rule {
+	if !store.prefs.Bool(preferenceRulesSaved) {
+		return append([]Rule(nil), DefaultRules...)
+	}
+
+	var rules []Rule
+	if str := store.prefs.String(preferenceRules); str != "" {
+		if err := json.Unmarshal([]byte(str), &rules); err != nil {
+			fyne.LogError("load alert rules failed", err)
+		}
+	}
+
+	return rules
+}
+
+func (store *RuleStore) save(rules []Rule) error {
+	data, err := json.Marshal(rules)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceRules, string(data))
+	store.prefs.SetBool(preferenceRulesSaved, true)
+
+	return nil
+}
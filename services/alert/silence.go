@@ -0,0 +1,267 @@
+package alert
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+const preferenceSilences = "alertSilences"
+
+// TimeLayout 维护窗口时间的输入格式
+const TimeLayout = "2006-01-02 15:04"
+
+type Recurrence int
+
+const (
+	RecurNone Recurrence = iota
+	RecurDaily
+	RecurWeekly
+)
+
+var Recurrences = []Recurrence{RecurNone, RecurDaily, RecurWeekly}
+
+func (recurrence Recurrence) String() string {
+	switch recurrence {
+	case RecurDaily:
+		return "每天"
+	case RecurWeekly:
+		return "每周"
+	}
+	return "单次"
+}
+
+// days 重复周期的天数 单次为0
+func (recurrence Recurrence) days() int {
+	switch recurrence {
+	case RecurDaily:
+		return 1
+	case RecurWeekly:
+		return 7
+	}
+	return 0
+}
+
+// Silence 维护窗口或临时静默 期间不发送通知 结果照常记录
+// Url Pattern Proxy为空表示不限 都为空时静默全部告警
+type Silence struct {
+	Name string
+	// Url 完全匹配
+	Url string
+	// Pattern 通配符 *匹配任意字符 如https://*.example.com/*
+	Pattern string
+	Proxy   string
+	Start   time.Time
+	End     time.Time
+	// Recurrence 重复时每个周期从Start对应时间开始 持续End-Start
+	Recurrence Recurrence
+	Comment    string
+}
+
+func (silence Silence) Validate() error {
+	if silence.Name == "" {
+		return errors.New("名称不能为空")
+	}
+	if !silence.End.After(silence.Start) {
+		return errors.New("结束时间必须晚于开始时间")
+	}
+	if days := silence.Recurrence.days(); days > 0 && silence.End.Sub(silence.Start) >= time.Duration(days)*24*time.Hour {
+		return errors.New("重复窗口的时长必须小于" + silence.Recurrence.String())
+	}
+	if _, err := patternRegexp(silence.Pattern); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// Active now是否在窗口内 重复窗口按now所在时区的日历计算 夏令时切换后仍从同一钟点开始
+func (silence Silence) Active(now time.Time) bool {
+	if now.Before(silence.Start) {
+		return false
+	}
+	days := silence.Recurrence.days()
+	if days == 0 {
+		return now.Before(silence.End)
+	}
+
+	//最近一个周期的开始时间 先取now当天的同一钟点 每周再退到同一星期几
+	first := silence.Start.In(now.Location())
+	year, month, day := now.Date()
+	start := time.Date(year, month, day, first.Hour(), first.Minute(), first.Second(), first.Nanosecond(), now.Location())
+	if days == 7 {
+		start = start.AddDate(0, 0, -(int(start.Weekday()-first.Weekday())+7)%7)
+	}
+	if start.After(now) {
+		start = start.AddDate(0, 0, -days)
+	}
+	return now.Sub(start) < silence.End.Sub(silence.Start)
+}
+
+// Expired 单次窗口已结束
+func (silence Silence) Expired(now time.Time) bool {
+	return silence.Recurrence == RecurNone && !now.Before(silence.End)
+}
+
+// Matches 是否作用于该告警
+func (silence Silence) Matches(alert Alert) bool {
+	if silence.Url != "" && silence.Url != alert.Url {
+		return false
+	}
+	if silence.Pattern != "" {
+		re, err := patternRegexp(silence.Pattern)
+		if err != nil || !re.MatchString(alert.Url) {
+			return false
+		}
+	}
+	if silence.Proxy != "" && (alert.Column.Source == "" || silence.Proxy != alert.Column.Proxy) {
+		return false
+	}
+
+	return true
+}
+
+// Describe 作用范围描述 labeler用于代理名称 可以为nil
+func (silence Silence) Describe(labeler status.Labeler) string {
+	var target []string
+	if silence.Url != "" {
+		target = append(target, silence.Url)
+	}
+	if silence.Pattern != "" {
+		target = append(target, silence.Pattern)
+	}
+	if silence.Proxy != "" {
+		target = append(target, "代理"+labeler.Proxy(silence.Proxy))
+	}
+	if len(target) == 0 {
+		target = append(target, "全部")
+	}
+
+	return silence.Recurrence.String() + " " + silence.Start.Format(TimeLayout) + "~" + silence.End.Format(TimeLayout) + " " + strings.Join(target, " ")
+}
+
+// patternRegexp 通配符转为正则 只支持*和?
+func patternRegexp(pattern string) (*regexp.Regexp, error) {
+	quoted := regexp.QuoteMeta(pattern)
+	quoted = strings.ReplaceAll(quoted, `\*`, ".*")
+	quoted = strings.ReplaceAll(quoted, `\?`, ".")
+	return regexp.Compile("^" + quoted + "$")
+}
+
+// SilenceStore 维护窗口 保存在fyne Preferences中
+type SilenceStore struct {
+	prefs fyne.Preferences
+	m     sync.Mutex
+}
+
+func NewSilenceStore(prefs fyne.Preferences) *SilenceStore {
+	return &SilenceStore{prefs: prefs}
+}
+
+func (store *SilenceStore) List() []Silence {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.list()
+}
+
+func (store *SilenceStore) Get(name string) (Silence, bool) {
+	for _, silence := range store.List() {
+		if silence.Name == name {
+			return silence, true
+		}
+	}
+
+	return Silence{}, false
+}
+
+// Save 新增或按名称覆盖
+func (store *SilenceStore) Save(silence Silence) error {
+	if err := silence.Validate(); err != nil {
+		return err
+	}
+
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	silences := store.list()
+	replaced := false
+	for i, s := range silences {
+		if s.Name == silence.Name {
+			silences[i] = silence
+			replaced = true
+		}
+	}
+	if !replaced {
+		silences = append(silences, silence)
+	}
+
+	return store.save(silences)
+}
+
+func (store *SilenceStore) Delete(name string) error {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	silences := store.list()
+	for i, s := range silences {
+		if s.Name == name {
+			return store.save(append(silences[:i], silences[i+1:]...))
+		}
+	}
+
+	return nil
+}
+
+// Prune 删除已结束的单次窗口
+func (store *SilenceStore) Prune(now time.Time) error {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	var silences []Silence
+	for _, s := range store.list() {
+		if !s.Expired(now) {
+			silences = append(silences, s)
+		}
+	}
+
+	return store.save(silences)
+}
+
+// Silenced 返回作用于该告警且正在生效的窗口
+func (store *SilenceStore) Silenced(alert Alert, now time.Time) (Silence, bool) {
+	for _, silence := range store.List() {
+		if silence.Active(now) && silence.Matches(alert) {
+			return silence, true
+		}
+	}
+
+	return Silence{}, false
+}
+
+func (store *SilenceStore) list() []Silence {
+	var silences []Silence
+	if str := store.prefs.String(preferenceSilences); str != "" {
+		if err := json.Unmarshal([]byte(str), &silences); err != nil {
+			fyne.LogError("load alert silences failed", err)
+		}
+	}
+
+	return silences
+}
+
+func (store *SilenceStore) save(silences []Silence) error {
+	data, err := json.Marshal(silences)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceSilences, string(data))
+
+	return nil
+}
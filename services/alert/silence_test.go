@@ -0,0 +1,91 @@
+package alert
+
+import (
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSilenceActive(t *testing.T) {
+	start := time.Date(2022, 7, 4, 22, 0, 0, 0, time.Local)
+	once := Silence{Name: "deploy", Start: start, End: start.Add(2 * time.Hour)}
+	assert.False(t, once.Active(start.Add(-time.Minute)))
+	assert.True(t, once.Active(start))
+	assert.True(t, once.Active(start.Add(119*time.Minute)))
+	assert.False(t, once.Active(start.Add(2*time.Hour)))
+	assert.True(t, once.Expired(start.Add(2*time.Hour)))
+
+	//每周一22点到24点
+	weekly := once
+	weekly.Recurrence = RecurWeekly
+	assert.True(t, weekly.Active(start.Add(7*24*time.Hour+time.Hour)))
+	assert.False(t, weekly.Active(start.Add(24*time.Hour+time.Hour)))
+	assert.False(t, weekly.Expired(start.Add(30*24*time.Hour)))
+
+	daily := once
+	daily.Recurrence = RecurDaily
+	assert.True(t, daily.Active(start.Add(3*24*time.Hour+30*time.Minute)))
+	assert.False(t, daily.Active(start.Add(3*24*time.Hour+3*time.Hour)))
+
+	daily.End = start.Add(25 * time.Hour)
+	assert.NotNil(t, daily.Validate())
+	assert.NotNil(t, Silence{Name: "bad", Start: start, End: start}.Validate())
+}
+
+func TestSilenceActiveDST(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	assert.Nil(t, err)
+
+	//3月13日切换夏令时 之后仍是每天凌晨2点半到3点半
+	start := time.Date(2022, 3, 1, 2, 30, 0, 0, newYork)
+	daily := Silence{Name: "backup", Start: start, End: start.Add(time.Hour), Recurrence: RecurDaily}
+	assert.True(t, daily.Active(time.Date(2022, 3, 20, 2, 45, 0, 0, newYork)))
+	assert.True(t, daily.Active(time.Date(2022, 3, 20, 3, 15, 0, 0, newYork)))
+	assert.False(t, daily.Active(time.Date(2022, 3, 20, 2, 15, 0, 0, newYork)))
+	assert.False(t, daily.Active(time.Date(2022, 3, 20, 3, 45, 0, 0, newYork)))
+
+	//每周二23点到次日1点 跨过11月6日的夏令时结束
+	start = time.Date(2022, 11, 1, 23, 0, 0, 0, newYork)
+	weekly := Silence{Name: "weekly", Start: start, End: start.Add(2 * time.Hour), Recurrence: RecurWeekly}
+	assert.True(t, weekly.Active(time.Date(2022, 11, 8, 23, 0, 0, 0, newYork)))
+	assert.True(t, weekly.Active(time.Date(2022, 11, 9, 0, 30, 0, 0, newYork)))
+	assert.False(t, weekly.Active(time.Date(2022, 11, 8, 22, 30, 0, 0, newYork)))
+	assert.False(t, weekly.Active(time.Date(2022, 11, 9, 23, 30, 0, 0, newYork)))
+}
+
+func TestSilenceMatches(t *testing.T) {
+	proxy := status.Column{Source: "local", Proxy: "socks5://127.0.0.1:8000"}
+	alert := Alert{Url: "https://api.example.com/health", Column: proxy}
+
+	assert.True(t, Silence{}.Matches(alert))
+	assert.True(t, Silence{Url: "https://api.example.com/health"}.Matches(alert))
+	assert.False(t, Silence{Url: "https://www.example.com"}.Matches(alert))
+	assert.True(t, Silence{Pattern: "https://*.example.com/*"}.Matches(alert))
+	assert.False(t, Silence{Pattern: "https://*.example.org/*"}.Matches(alert))
+	assert.True(t, Silence{Proxy: proxy.Proxy}.Matches(alert))
+	//针对整个url的告警不属于某个代理
+	assert.False(t, Silence{Proxy: proxy.Proxy}.Matches(Alert{Url: alert.Url}))
+}
+
+func TestSilenceStore(t *testing.T) {
+	store := NewSilenceStore(test.NewApp().Preferences())
+	now := time.Now()
+
+	assert.Nil(t, store.Save(Silence{Name: "deploy", Pattern: "https://*.example.com/*", Start: now.Add(-time.Minute), End: now.Add(time.Hour)}))
+	assert.Nil(t, store.Save(Silence{Name: "old", Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}))
+
+	silence, ok := store.Silenced(Alert{Url: "https://api.example.com/health"}, now)
+	assert.True(t, ok)
+	assert.Equal(t, "deploy", silence.Name)
+	_, ok = store.Silenced(Alert{Url: "https://www.google.com"}, now)
+	assert.False(t, ok)
+
+	assert.Nil(t, store.Prune(now))
+	assert.Len(t, store.List(), 1)
+	assert.Nil(t, store.Delete("deploy"))
+	assert.Empty(t, store.List())
+}
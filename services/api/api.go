@@ -0,0 +1,433 @@
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+// TokenHeader 除Authorization: Bearer外也可以用该请求头传递token
+const TokenHeader = "X-Api-Token"
+
+// maxBody 请求体上限
+const maxBody = 1 << 20
+
+// Server 本地HTTP/JSON接口 与grpc服务的操作一致 所有请求需要token
+//
+//	GET    /api/urls                     地址列表
+//	POST   /api/urls      {url,interval} 添加或修改地址 interval为毫秒数或时长如30s
+//	DELETE /api/urls?url=                删除地址
+//	GET    /api/proxies                  代理列表
+//	POST   /api/proxies   {proxy,force}  测试后添加代理 force为true时跳过测试
+//	DELETE /api/proxies?proxy=           删除代理
+//	GET    /api/monitor                  运行中的服务
+//	POST   /api/monitor/start            启动全部服务的监控
+//	POST   /api/monitor/stop             停止监控
+//	GET    /api/status                   最新的url×代理状态矩阵
+type Server struct {
+	// ProbeUrl 测试代理时访问的地址 为空时使用proxy.DefaultProbeUrl
+	ProbeUrl string
+
+	backend rpc.MonitorBackend
+	monitor *rpc.Aggregator
+	bus     *rpc.EventBus
+	labels  *proxy.LabelStore
+	labeler status.Labeler
+	matrix  *status.Matrix
+	sub     *rpc.Subscription
+	token   string
+}
+
+// NewServer token不能为空 状态矩阵由bus上的监控结果更新 直到Close labels可以为nil
+func NewServer(backend rpc.MonitorBackend, monitor *rpc.Aggregator, bus *rpc.EventBus, labels *proxy.LabelStore, token string) (*Server, error) {
+	if strings.TrimSpace(token) == "" {
+		return nil, errors.New("token不能为空")
+	}
+
+	server := &Server{backend: backend, monitor: monitor, bus: bus, labels: labels, matrix: status.NewMatrix(), token: token}
+	if labels != nil {
+		server.labeler = labels.Name
+	}
+	server.sub = bus.Subscribe("api", 1000)
+	go func() {
+		for res := range server.sub.C {
+			server.matrix.Update(res)
+		}
+	}()
+
+	return server, nil
+}
+
+// Close 停止更新状态矩阵 不关闭http服务
+func (server *Server) Close() {
+	server.sub.Unsubscribe()
+}
+
+// Handler 全部接口 未通过token验证返回401
+func (server *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/urls", server.urls)
+	mux.HandleFunc("/api/proxies", server.proxies)
+	mux.HandleFunc("/api/monitor", server.monitorState)
+	mux.HandleFunc("/api/monitor/start", server.monitorStart)
+	mux.HandleFunc("/api/monitor/stop", server.monitorStop)
+	mux.HandleFunc("/api/status", server.status)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !server.authorized(r) {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			writeError(w, http.StatusUnauthorized, errors.New("token无效"))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (server *Server) authorized(r *http.Request) bool {
+	token := r.Header.Get(TokenHeader)
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(server.token)) == 1
+}
+
+// Serve 在addr上提供接口 监听失败时立即返回错误 关闭返回的Server即停止
+func Serve(addr string, server *Server) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	httpServer := &http.Server{Handler: server.Handler(), ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := httpServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fyne.LogError("api server stopped", err)
+		}
+	}()
+
+	return httpServer, nil
+}
+
+func writeJson(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		fyne.LogError("write api response failed", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJson(w, code, map[string]string{"error": err.Error()})
+}
+
+func methodNotAllowed(w http.ResponseWriter, allow ...string) {
+	w.Header().Set("Allow", strings.Join(allow, ", "))
+	writeError(w, http.StatusMethodNotAllowed, errors.New("不支持的请求方法"))
+}
+
+func readJson(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxBody))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(v); err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("请求格式错误:"+err.Error()))
+		return false
+	}
+	return true
+}
+
+// Url interval为时长 intervalMs为毫秒数
+type Url struct {
+	Url        string `json:"url"`
+	Interval   string `json:"interval"`
+	IntervalMs int32  `json:"intervalMs"`
+}
+
+func newUrl(url string, interval int32) Url {
+	return Url{Url: url, Interval: rpc.FormatInterval(interval), IntervalMs: interval}
+}
+
+func (server *Server) urls(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		urlInterval, err := server.backend.ListUrlInterval()
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		urls := make([]Url, 0, len(urlInterval))
+		for url, interval := range urlInterval {
+			urls = append(urls, newUrl(url, interval))
+		}
+		sort.Slice(urls, func(i, j int) bool {
+			return urls[i].Url < urls[j].Url
+		})
+		writeJson(w, http.StatusOK, urls)
+	case http.MethodPost:
+		var req struct {
+			Url      string `json:"url"`
+			Interval string `json:"interval"`
+		}
+		if !readJson(w, r, &req) {
+			return
+		}
+		if req.Interval == "" {
+			req.Interval = rpc.DefaultInterval.String()
+		}
+		if err := rpc.ValidateUrl(req.Url); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		interval, err := rpc.ParseInterval(req.Interval)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		if err = server.backend.SetUrl(req.Url, interval); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJson(w, http.StatusOK, newUrl(req.Url, interval))
+	case http.MethodDelete:
+		url := r.URL.Query().Get("url")
+		if url == "" {
+			writeError(w, http.StatusBadRequest, errors.New("缺少url参数"))
+			return
+		}
+		urlInterval, err := server.backend.ListUrlInterval()
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		if _, ok := urlInterval[url]; !ok {
+			writeError(w, http.StatusNotFound, errors.New("地址不存在:"+url))
+			return
+		}
+		if err = server.backend.DeleteUrl(url); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJson(w, http.StatusOK, map[string]string{"removed": url})
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+// Proxy 代理与标签
+type Proxy struct {
+	Proxy  string `json:"proxy"`
+	Name   string `json:"name,omitempty"`
+	Region string `json:"region,omitempty"`
+	Group  string `json:"group,omitempty"`
+}
+
+// ProxyTest 添加代理的结果 force时不测试 Tested为false
+type ProxyTest struct {
+	Proxy     string `json:"proxy"`
+	Tested    bool   `json:"tested"`
+	LatencyMs int64  `json:"latencyMs,omitempty"`
+	EgressIp  string `json:"egressIp,omitempty"`
+}
+
+func (server *Server) proxies(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		raws, err := server.backend.ListProxy()
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		proxies := make([]Proxy, 0, len(raws))
+		for _, raw := range raws {
+			p := Proxy{Proxy: raw}
+			if server.labels != nil {
+				label := server.labels.Get(raw)
+				p.Name, p.Region, p.Group = label.Name, label.Region, label.Group
+			}
+			proxies = append(proxies, p)
+		}
+		writeJson(w, http.StatusOK, proxies)
+	case http.MethodPost:
+		var req struct {
+			Proxy string `json:"proxy"`
+			Force bool   `json:"force"`
+		}
+		if !readJson(w, r, &req) {
+			return
+		}
+		raw := strings.TrimSpace(req.Proxy)
+		if _, err := proxy.Parse(raw); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		//与界面和命令行一致 测试失败时拒绝保存 除非force
+		added := ProxyTest{Proxy: raw}
+		if !req.Force {
+			ctx, cancel := context.WithTimeout(r.Context(), 15*time.Second)
+			res := proxy.Test(ctx, raw, server.ProbeUrl)
+			cancel()
+			if res.Err != nil {
+				writeError(w, http.StatusUnprocessableEntity, errors.New("代理不可用 "+res.String()+" 使用force强制添加"))
+				return
+			}
+			added.Tested, added.LatencyMs, added.EgressIp = true, res.Latency.Milliseconds(), res.EgressIp
+		}
+		if err := server.backend.SetProxy(raw); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		writeJson(w, http.StatusOK, added)
+	case http.MethodDelete:
+		raw := r.URL.Query().Get("proxy")
+		if raw == "" {
+			writeError(w, http.StatusBadRequest, errors.New("缺少proxy参数"))
+			return
+		}
+		raws, err := server.backend.ListProxy()
+		if err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		found := false
+		for _, v := range raws {
+			found = found || v == raw
+		}
+		if !found {
+			writeError(w, http.StatusNotFound, errors.New("代理不存在:"+raw))
+			return
+		}
+		if err = server.backend.DeleteProxy(raw); err != nil {
+			writeError(w, http.StatusBadGateway, err)
+			return
+		}
+		if server.labels != nil {
+			if err = server.labels.Delete(raw); err != nil {
+				fyne.LogError("delete proxy label failed", err)
+			}
+		}
+		writeJson(w, http.StatusOK, map[string]string{"removed": raw})
+	default:
+		methodNotAllowed(w, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+// MonitorState running为运行中的服务 errors为启动或停止失败的服务
+type MonitorState struct {
+	Sources []string          `json:"sources"`
+	Running []string          `json:"running"`
+	Errors  map[string]string `json:"errors,omitempty"`
+}
+
+func (server *Server) monitorState(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+	writeJson(w, http.StatusOK, server.state(nil))
+}
+
+func (server *Server) state(errs map[string]error) MonitorState {
+	state := MonitorState{Sources: server.monitor.Sources(), Running: server.monitor.Running()}
+	if state.Running == nil {
+		state.Running = []string{}
+	}
+	if len(errs) > 0 {
+		state.Errors = make(map[string]string, len(errs))
+		for name, err := range errs {
+			state.Errors[name] = err.Error()
+		}
+	}
+	return state
+}
+
+// monitorStart 全部服务启动失败时返回502
+func (server *Server) monitorStart(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	errs := server.monitor.StartMonitor(server.bus, server.monitor.Sources())
+	state := server.state(errs)
+	code := http.StatusOK
+	if len(state.Running) == 0 {
+		code = http.StatusBadGateway
+	}
+	writeJson(w, code, state)
+}
+
+func (server *Server) monitorStop(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		methodNotAllowed(w, http.MethodPost)
+		return
+	}
+	errs := server.monitor.StopMonitor()
+	state := server.state(errs)
+	code := http.StatusOK
+	if len(errs) > 0 {
+		code = http.StatusBadGateway
+	}
+	writeJson(w, code, state)
+}
+
+// Cell url在一个服务的一个代理上的最近一次结果
+type Cell struct {
+	Source    string    `json:"source"`
+	Proxy     string    `json:"proxy"`
+	ProxyName string    `json:"proxyName"`
+	Status    string    `json:"status"`
+	Ok        bool      `json:"ok"`
+	Message   string    `json:"message"`
+	Time      time.Time `json:"time"`
+}
+
+// Row 一个url的全部结果 还没有结果的列不输出
+type Row struct {
+	Url   string `json:"url"`
+	Cells []Cell `json:"cells"`
+}
+
+func (server *Server) status(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, http.MethodGet)
+		return
+	}
+
+	//与告警一致 删除服务上已不存在的url与代理 获取失败时不删除
+	if urls, err := server.backend.ListUrl(); err == nil {
+		if proxies, err := server.backend.ListProxy(); err == nil {
+			server.matrix.Retain(server.backend.Name(), urls, proxies)
+		}
+	}
+	columns := server.matrix.Columns()
+	rows := make([]Row, 0)
+	for _, url := range server.matrix.Urls() {
+		row := Row{Url: url, Cells: make([]Cell, 0, len(columns))}
+		for _, column := range columns {
+			cell, ok := server.matrix.Cell(url, column)
+			if !ok {
+				continue
+			}
+			row.Cells = append(row.Cells, Cell{
+				Source:    column.Source,
+				Proxy:     proxy.Redact(column.Proxy),
+				ProxyName: server.labeler.Proxy(column.Proxy),
+				Status:    cell.String(),
+				Ok:        cell.OK(),
+				Message:   cell.Message,
+				Time:      cell.Time,
+			})
+		}
+		rows = append(rows, row)
+	}
+	writeJson(w, http.StatusOK, rows)
+}
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServer(t *testing.T) {
+	_, err := NewServer(nil, nil, nil, nil, " ")
+	assert.NotNil(t, err)
+
+	backend := rpc.NewMemoryBackend()
+	monitor := rpc.NewAggregator(func() []rpc.MonitorBackend {
+		return []rpc.MonitorBackend{backend}
+	})
+	labels := proxy.NewLabelStore(test.NewApp().Preferences())
+	assert.Nil(t, labels.Save(proxy.Label{Proxy: "http://1.2.3.4:80", Name: "东京"}))
+	bus := rpc.NewEventBus()
+	server, err := NewServer(backend, monitor, bus, labels, "secret")
+	assert.Nil(t, err)
+	defer func() {
+		server.Close()
+		assert.Zero(t, bus.Subscribers())
+	}()
+	//代理测试经过本地的http代理 直接返回出口ip
+	fakeProxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("5.6.7.8"))
+	}))
+	defer fakeProxy.Close()
+	server.ProbeUrl = "http://probe.test/ip"
+	httpServer := httptest.NewServer(server.Handler())
+	defer httpServer.Close()
+
+	call := func(method, path, body string, v interface{}) int {
+		req, err := http.NewRequest(method, httpServer.URL+path, strings.NewReader(body))
+		assert.Nil(t, err)
+		req.Header.Set("Authorization", "Bearer secret")
+		res, err := http.DefaultClient.Do(req)
+		assert.Nil(t, err)
+		defer res.Body.Close()
+		if v != nil {
+			assert.Nil(t, json.NewDecoder(res.Body).Decode(v))
+		}
+		return res.StatusCode
+	}
+
+	res, err := http.Get(httpServer.URL + "/api/urls")
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	req, _ := http.NewRequest(http.MethodGet, httpServer.URL+"/api/urls", nil)
+	req.Header.Set(TokenHeader, "secret")
+	res, err = http.DefaultClient.Do(req)
+	assert.Nil(t, err)
+	res.Body.Close()
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	var u Url
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/urls", `{"url":"https://a.com","interval":"200ms"}`, &u))
+	assert.Equal(t, Url{Url: "https://a.com", Interval: "200ms", IntervalMs: 200}, u)
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/api/urls", `{"url":"ftp://a.com"}`, nil))
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/api/urls", `{"link":"https://a.com"}`, nil))
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/urls", `{"url":"https://b.com"}`, nil))
+	var urls []Url
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/urls", "", &urls))
+	assert.Equal(t, []Url{u, {Url: "https://b.com", Interval: "30s", IntervalMs: 30000}}, urls)
+	assert.Equal(t, http.StatusOK, call(http.MethodDelete, "/api/urls?url="+url.QueryEscape("https://b.com"), "", nil))
+	assert.Equal(t, http.StatusNotFound, call(http.MethodDelete, "/api/urls?url="+url.QueryEscape("https://b.com"), "", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, call(http.MethodPut, "/api/urls", "", nil))
+
+	assert.Equal(t, http.StatusBadRequest, call(http.MethodPost, "/api/proxies", `{"proxy":"ftp://1.2.3.4"}`, nil))
+	assert.Equal(t, http.StatusUnprocessableEntity, call(http.MethodPost, "/api/proxies", `{"proxy":"http://127.0.0.1:1"}`, nil))
+	var added ProxyTest
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/proxies", `{"proxy":"http://1.2.3.4:80","force":true}`, &added))
+	assert.Equal(t, ProxyTest{Proxy: "http://1.2.3.4:80"}, added)
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/proxies", `{"proxy":"`+fakeProxy.URL+`"}`, &added))
+	assert.True(t, added.Tested)
+	assert.Equal(t, "5.6.7.8", added.EgressIp)
+	var proxies []Proxy
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/proxies", "", &proxies))
+	assert.Equal(t, []Proxy{{Proxy: "http://1.2.3.4:80", Name: "东京"}, {Proxy: fakeProxy.URL}}, proxies)
+	assert.Equal(t, http.StatusOK, call(http.MethodDelete, "/api/proxies?proxy="+url.QueryEscape("http://1.2.3.4:80"), "", nil))
+	assert.Equal(t, http.StatusOK, call(http.MethodDelete, "/api/proxies?proxy="+url.QueryEscape(fakeProxy.URL), "", nil))
+	assert.True(t, labels.Get("http://1.2.3.4:80").Empty())
+
+	var state MonitorState
+	assert.Equal(t, http.StatusMethodNotAllowed, call(http.MethodGet, "/api/monitor/start", "", nil))
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/monitor/start", "", &state))
+	assert.Equal(t, []string{"memory"}, state.Running)
+
+	var rows []Row
+	assert.Eventually(t, func() bool {
+		rows = nil
+		call(http.MethodGet, "/api/status", "", &rows)
+		return len(rows) == 1 && len(rows[0].Cells) == 1
+	}, 5*time.Second, 50*time.Millisecond)
+	assert.Equal(t, "https://a.com", rows[0].Url)
+	assert.Equal(t, "直连", rows[0].Cells[0].ProxyName)
+	assert.True(t, rows[0].Cells[0].Ok)
+
+	assert.Equal(t, http.StatusOK, call(http.MethodPost, "/api/monitor/stop", "", &state))
+	assert.Equal(t, []string{}, state.Running)
+
+	//删除的url不再返回
+	assert.Nil(t, backend.DeleteUrl("https://a.com"))
+	rows = nil
+	assert.Equal(t, http.StatusOK, call(http.MethodGet, "/api/status", "", &rows))
+	assert.Empty(t, rows)
+}
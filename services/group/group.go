@@ -0,0 +1,235 @@
+package group
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+const preferenceGroups = "urlGroups"
+
+// Ungrouped 没有分组的url显示在该分组下
+const Ungrouped = "未分组"
+
+// Meta url的分组与标签 只保存在客户端
+type Meta struct {
+	Url   string
+	Group string
+	Tags  []string
+}
+
+// GroupName 没有分组时返回Ungrouped
+func (meta Meta) GroupName() string {
+	if meta.Group == "" {
+		return Ungrouped
+	}
+	return meta.Group
+}
+
+func (meta Meta) HasTag(tag string) bool {
+	for _, t := range meta.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseTags 逗号或空格分隔 去掉空白与重复
+func ParseTags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tag := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == '，' || r == ' '
+	}) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// Filter 按分组或标签筛选 为空不限
+type Filter struct {
+	Group string
+	Tag   string
+}
+
+func (filter Filter) Match(meta Meta) bool {
+	if filter.Group != "" && filter.Group != meta.GroupName() {
+		return false
+	}
+	if filter.Tag != "" && !meta.HasTag(filter.Tag) {
+		return false
+	}
+	return true
+}
+
+// MatchUrl url是否满足筛选条件 index为Index的返回值
+func (filter Filter) MatchUrl(index map[string]Meta, url string) bool {
+	meta, ok := index[url]
+	if !ok {
+		meta.Url = url
+	}
+	return filter.Match(meta)
+}
+
+// Store url分组与标签 保存在fyne Preferences中
+type Store struct {
+	prefs     fyne.Preferences
+	m         sync.Mutex
+	onChanged func()
+}
+
+func NewStore(prefs fyne.Preferences) *Store {
+	return &Store{prefs: prefs}
+}
+
+// SetOnChanged 分组或标签变化时回调
+func (store *Store) SetOnChanged(f func()) {
+	store.m.Lock()
+	defer store.m.Unlock()
+	store.onChanged = f
+}
+
+func (store *Store) List() []Meta {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.list()
+}
+
+// Get 没有设置过时返回只有Url的Meta
+func (store *Store) Get(url string) Meta {
+	for _, meta := range store.List() {
+		if meta.Url == url {
+			return meta
+		}
+	}
+
+	return Meta{Url: url}
+}
+
+// Index 按url索引 没有设置过的url不在其中
+func (store *Store) Index() map[string]Meta {
+	metas := store.List()
+	index := make(map[string]Meta, len(metas))
+	for _, meta := range metas {
+		index[meta.Url] = meta
+	}
+
+	return index
+}
+
+// Groups 全部分组名 不含Ungrouped
+func (store *Store) Groups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, meta := range store.List() {
+		if meta.Group != "" && !seen[meta.Group] {
+			seen[meta.Group] = true
+			groups = append(groups, meta.Group)
+		}
+	}
+	sort.Strings(groups)
+
+	return groups
+}
+
+func (store *Store) Tags() []string {
+	seen := make(map[string]bool)
+	var tags []string
+	for _, meta := range store.List() {
+		for _, tag := range meta.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// Save 新增或按url覆盖 分组与标签都为空时删除
+func (store *Store) Save(meta Meta) error {
+	meta.Group = strings.TrimSpace(meta.Group)
+	if meta.Group == Ungrouped {
+		meta.Group = ""
+	}
+
+	return store.update(func(metas []Meta) []Meta {
+		metas = remove(metas, meta.Url)
+		if meta.Group != "" || len(meta.Tags) > 0 {
+			metas = append(metas, meta)
+		}
+		return metas
+	})
+}
+
+func (store *Store) Delete(url string) error {
+	return store.update(func(metas []Meta) []Meta {
+		return remove(metas, url)
+	})
+}
+
+// Rename url修改后保留分组与标签
+func (store *Store) Rename(oldUrl, newUrl string) error {
+	return store.update(func(metas []Meta) []Meta {
+		metas = remove(metas, newUrl)
+		for i := range metas {
+			if metas[i].Url == oldUrl {
+				metas[i].Url = newUrl
+			}
+		}
+		return metas
+	})
+}
+
+func (store *Store) update(f func([]Meta) []Meta) error {
+	store.m.Lock()
+	err := store.save(f(store.list()))
+	onChanged := store.onChanged
+	store.m.Unlock()
+
+	if err == nil && onChanged != nil {
+		onChanged()
+	}
+
+	return err
+}
+
+func remove(metas []Meta, url string) []Meta {
+	for i, meta := range metas {
+		if meta.Url == url {
+			return append(metas[:i], metas[i+1:]...)
+		}
+	}
+	return metas
+}
+
+func (store *Store) list() []Meta {
+	var metas []Meta
+	if str := store.prefs.String(preferenceGroups); str != "" {
+		if err := json.Unmarshal([]byte(str), &metas); err != nil {
+			fyne.LogError("load url groups failed", err)
+		}
+	}
+
+	return metas
+}
+
+func (store *Store) save(metas []Meta) error {
+	data, err := json.Marshal(metas)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceGroups, string(data))
+
+	return nil
+}
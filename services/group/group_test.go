@@ -0,0 +1,45 @@
+package group
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	assert.Equal(t, []string{"prod", "api", "cn"}, ParseTags(" prod,api，cn api "))
+	assert.Nil(t, ParseTags(""))
+}
+
+func TestStore(t *testing.T) {
+	store := NewStore(test.NewApp().Preferences())
+	changed := 0
+	store.SetOnChanged(func() {
+		changed++
+	})
+
+	assert.Nil(t, store.Save(Meta{Url: "https://www.baidu.com", Group: "搜索", Tags: []string{"cn"}}))
+	assert.Nil(t, store.Save(Meta{Url: "https://www.google.com", Group: "搜索", Tags: []string{"global"}}))
+	assert.Nil(t, store.Save(Meta{Url: "https://api.example.com", Tags: []string{"cn", "api"}}))
+	assert.Equal(t, 3, changed)
+	assert.Equal(t, []string{"搜索"}, store.Groups())
+	assert.Equal(t, []string{"api", "cn", "global"}, store.Tags())
+
+	index := store.Index()
+	assert.True(t, Filter{Group: "搜索", Tag: "cn"}.MatchUrl(index, "https://www.baidu.com"))
+	assert.False(t, Filter{Group: "搜索"}.MatchUrl(index, "https://api.example.com"))
+	assert.True(t, Filter{Group: Ungrouped}.MatchUrl(index, "https://api.example.com"))
+	//没有设置过的url属于未分组
+	assert.True(t, Filter{Group: Ungrouped}.MatchUrl(index, "https://other.example.com"))
+	assert.True(t, Filter{}.MatchUrl(index, "https://other.example.com"))
+
+	assert.Nil(t, store.Rename("https://www.baidu.com", "https://baidu.com"))
+	assert.Equal(t, "搜索", store.Get("https://baidu.com").Group)
+	assert.Equal(t, Meta{Url: "https://www.baidu.com"}, store.Get("https://www.baidu.com"))
+
+	//分组与标签都为空时删除
+	assert.Nil(t, store.Save(Meta{Url: "https://baidu.com", Group: Ungrouped}))
+	assert.Nil(t, store.Delete("https://api.example.com"))
+	assert.Len(t, store.List(), 1)
+}
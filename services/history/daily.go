@@ -0,0 +1,201 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"go.etcd.io/bbolt"
+)
+
+// bucketDaily 每个url一个子bucket key为本地日期 value为当天的检测汇总 清理原始记录时保留
+const bucketDaily = "daily"
+
+// DailyRetention 每日汇总的保留时间 远长于原始记录
+const DailyRetention = 400 * 24 * time.Hour
+
+const dateLayout = "2006-01-02"
+
+// Day 一个url一天的检测汇总 同一服务同一时间的全部代理为一次检测
+// Up为没有全部失败的检测次数 Degraded为部分代理失败的检测次数
+type Day struct {
+	Date     time.Time `json:"-"`
+	Checks   int
+	Up       int
+	Degraded int
+}
+
+// check 一次检测的成功与失败代理数
+type check struct {
+	url    string
+	source string
+	time   time.Time
+	ok     int
+	failed int
+}
+
+func (c *check) add(record Record) {
+	if result.Parse(record.Result).OK() {
+		c.ok++
+	} else {
+		c.failed++
+	}
+}
+
+func (c *check) same(record Record) bool {
+	return c.url == record.Url && c.source == record.Source && c.time.Equal(record.Time)
+}
+
+// addDaily 把检测计入当天汇总 日期按本地时间
+func addDaily(tx *bbolt.Tx, checks []*check) error {
+	root, err := tx.CreateBucketIfNotExists([]byte(bucketDaily))
+	if err != nil {
+		return err
+	}
+
+	for _, c := range checks {
+		bucket, err := root.CreateBucketIfNotExists([]byte(c.url))
+		if err != nil {
+			return err
+		}
+		key := []byte(c.time.In(time.Local).Format(dateLayout))
+		var day Day
+		if v := bucket.Get(key); v != nil {
+			if err = json.Unmarshal(v, &day); err != nil {
+				return err
+			}
+		}
+		day.Checks++
+		if c.ok > 0 {
+			day.Up++
+			if c.failed > 0 {
+				day.Degraded++
+			}
+		}
+		value, err := json.Marshal(day)
+		if err != nil {
+			return err
+		}
+		if err = bucket.Put(key, value); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// groupChecks 同一次检测的记录合并 一次监控结果的记录总是一起写入
+func groupChecks(records []Record) []*check {
+	type checkKey struct {
+		url    string
+		source string
+		nanos  int64
+	}
+	var checks []*check
+	index := make(map[checkKey]*check)
+	for _, record := range records {
+		k := checkKey{url: record.Url, source: record.Source, nanos: record.Time.UnixNano()}
+		c, ok := index[k]
+		if !ok {
+			c = &check{url: record.Url, source: record.Source, time: record.Time}
+			index[k] = c
+			checks = append(checks, c)
+		}
+		c.add(record)
+	}
+	return checks
+}
+
+// rebuildDaily 旧数据库没有每日汇总 由已有记录生成一次
+func rebuildDaily(tx *bbolt.Tx) error {
+	root := tx.Bucket([]byte(bucketHistory))
+	if root == nil || tx.Bucket([]byte(bucketDaily)) != nil {
+		return nil
+	}
+
+	var checks []*check
+	err := root.ForEach(func(url, _ []byte) error {
+		//同一url内按时间有序 同一次检测的记录相邻
+		var current *check
+		return root.Bucket(url).ForEach(func(_, v []byte) error {
+			var record Record
+			if err := json.Unmarshal(v, &record); err != nil {
+				return err
+			}
+			if current == nil || !current.same(record) {
+				current = &check{url: record.Url, source: record.Source, time: record.Time}
+				checks = append(checks, current)
+			}
+			current.add(record)
+			return nil
+		})
+	})
+	if err != nil {
+		return err
+	}
+
+	return addDaily(tx, checks)
+}
+
+// Daily 每个url从from所在日期开始有检测的每日汇总 按日期升序
+func (store *Store) Daily(urls []string, from time.Time) (map[string][]Day, error) {
+	days := make(map[string][]Day, len(urls))
+	err := store.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(bucketDaily))
+		if root == nil {
+			return nil
+		}
+
+		start := []byte(from.In(time.Local).Format(dateLayout))
+		for _, url := range urls {
+			bucket := root.Bucket([]byte(url))
+			if bucket == nil {
+				continue
+			}
+			c := bucket.Cursor()
+			for k, v := c.Seek(start); k != nil; k, v = c.Next() {
+				var day Day
+				if err := json.Unmarshal(v, &day); err != nil {
+					return err
+				}
+				date, err := time.ParseInLocation(dateLayout, string(k), time.Local)
+				if err != nil {
+					return err
+				}
+				day.Date = date
+				days[url] = append(days[url], day)
+			}
+		}
+
+		return nil
+	})
+
+	return days, err
+}
+
+// PruneDaily 删除before所在日期之前的每日汇总
+func (store *Store) PruneDaily(before time.Time) error {
+	return store.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(bucketDaily))
+		if root == nil {
+			return nil
+		}
+
+		end := []byte(before.In(time.Local).Format(dateLayout))
+		return root.ForEach(func(url, _ []byte) error {
+			bucket := root.Bucket(url)
+			var keys [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+				keys = append(keys, k)
+			}
+			for _, k := range keys {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,308 @@
+package history
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"go.etcd.io/bbolt"
+)
+
+// bucketHistory 每个url一个子bucket key为时间+序号 按时间有序
+const bucketHistory = "history"
+
+// Record 一次检测中一个代理的结果 Proxy为空表示直连
+type Record struct {
+	Time   time.Time
+	Source string
+	Url    string
+	Proxy  string
+	Result string
+}
+
+// Query 查询条件 切片为空表示不限 From To为零值表示不限
+type Query struct {
+	Urls    []string
+	Proxies []string
+	Sources []string
+	From    time.Time
+	To      time.Time
+	// Limit 最多返回条数 超出时保留最新的 0表示不限
+	Limit int
+}
+
+func (q Query) match(record Record) bool {
+	return matchAny(q.Proxies, record.Proxy) && matchAny(q.Sources, record.Source)
+}
+
+func matchAny(values []string, v string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Store 监控结果历史 保存在本地boltdb
+type Store struct {
+	db *bbolt.DB
+}
+
+// Open 打开path处的数据库 不存在时创建
+func Open(path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	db, err := bbolt.Open(path, 0600, &bbolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, err
+	}
+	if err = db.Update(rebuildDaily); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+func (store *Store) Close() error {
+	return store.db.Close()
+}
+
+// maxBatch Save一次写入的最多结果数
+const maxBatch = 500
+
+// Records 一次监控结果 每个代理一条
+func Records(res *rpc.MonitorResult) []Record {
+	if res == nil || res.MonitorResponse == nil {
+		return nil
+	}
+
+	t := res.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	records := make([]Record, 0, len(res.Result))
+	for proxy, result := range res.Result {
+		records = append(records, Record{Time: t, Source: res.Source, Url: res.Url, Proxy: proxy, Result: result})
+	}
+
+	return records
+}
+
+// Add 记录一次监控结果
+func (store *Store) Add(res *rpc.MonitorResult) error {
+	return store.AddRecords(Records(res)...)
+}
+
+// Save 保存订阅中的全部结果直到订阅关闭 已到达的结果合并为一次写入 减少fsync
+func (store *Store) Save(sub *rpc.Subscription) {
+	for res := range sub.C {
+		records := Records(res)
+	drain:
+		for n := 1; n < maxBatch; n++ {
+			select {
+			case res, ok := <-sub.C:
+				if !ok {
+					break drain
+				}
+				records = append(records, Records(res)...)
+			default:
+				break drain
+			}
+		}
+		if err := store.AddRecords(records...); err != nil {
+			fyne.LogError("save history failed", err)
+		}
+	}
+}
+
+func (store *Store) AddRecords(records ...Record) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	return store.db.Update(func(tx *bbolt.Tx) error {
+		root, err := tx.CreateBucketIfNotExists([]byte(bucketHistory))
+		if err != nil {
+			return err
+		}
+
+		for _, record := range records {
+			bucket, err := root.CreateBucketIfNotExists([]byte(record.Url))
+			if err != nil {
+				return err
+			}
+			seq, err := bucket.NextSequence()
+			if err != nil {
+				return err
+			}
+			value, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if err = bucket.Put(recordKey(record.Time, seq), value); err != nil {
+				return err
+			}
+		}
+
+		return addDaily(tx, groupChecks(records))
+	})
+}
+
+// Query 按时间升序返回符合条件的记录
+func (store *Store) Query(q Query) ([]Record, error) {
+	var records []Record
+	err := store.Each(q, func(record Record) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Time.Before(records[j].Time)
+	})
+	if q.Limit > 0 && len(records) > q.Limit {
+		records = records[len(records)-q.Limit:]
+	}
+
+	return records, nil
+}
+
+// Each 逐条遍历符合条件的记录 不加载到内存 忽略q.Limit
+// 按url依次遍历 同一url内按时间升序 f返回错误时停止
+func (store *Store) Each(q Query, f func(Record) error) error {
+	return store.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(bucketHistory))
+		if root == nil {
+			return nil
+		}
+
+		urls := q.Urls
+		if len(urls) == 0 {
+			if err := root.ForEach(func(k, _ []byte) error {
+				urls = append(urls, string(k))
+				return nil
+			}); err != nil {
+				return err
+			}
+		}
+
+		for _, url := range urls {
+			bucket := root.Bucket([]byte(url))
+			if bucket == nil {
+				continue
+			}
+
+			c := bucket.Cursor()
+			k, v := c.First()
+			if !q.From.IsZero() {
+				k, v = c.Seek(recordKey(q.From, 0))
+			}
+			for ; k != nil; k, v = c.Next() {
+				if !q.To.IsZero() && bytes.Compare(k, recordKey(q.To, 0)) >= 0 {
+					break
+				}
+				var record Record
+				if err := json.Unmarshal(v, &record); err != nil {
+					return err
+				}
+				if !q.match(record) {
+					continue
+				}
+				if err := f(record); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+}
+
+// Urls 有历史记录的url
+func (store *Store) Urls() (urls []string, err error) {
+	err = store.db.View(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(bucketHistory))
+		if root == nil {
+			return nil
+		}
+		return root.ForEach(func(k, _ []byte) error {
+			urls = append(urls, string(k))
+			return nil
+		})
+	})
+
+	return
+}
+
+// Prune 删除before之前的记录 返回删除条数 每日汇总由PruneDaily删除
+func (store *Store) Prune(before time.Time) (deleted int, err error) {
+	err = store.db.Update(func(tx *bbolt.Tx) error {
+		root := tx.Bucket([]byte(bucketHistory))
+		if root == nil {
+			return nil
+		}
+
+		end := recordKey(before, 0)
+		return root.ForEach(func(url, _ []byte) error {
+			bucket := root.Bucket(url)
+			//遍历时删除会跳过记录 先收集key
+			var keys [][]byte
+			c := bucket.Cursor()
+			for k, _ := c.First(); k != nil && bytes.Compare(k, end) < 0; k, _ = c.Next() {
+				keys = append(keys, k)
+			}
+			for _, k := range keys {
+				if err := bucket.Delete(k); err != nil {
+					return err
+				}
+			}
+			deleted += len(keys)
+			return nil
+		})
+	})
+
+	return
+}
+
+// RunPrune 立即删除retention之前的记录与DailyRetention之前的每日汇总 之后每interval删除一次
+// 直到ctx取消 失败只记录日志
+func (store *Store) RunPrune(ctx context.Context, retention, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := store.Prune(time.Now().Add(-retention)); err != nil {
+			fyne.LogError("prune history failed", err)
+		}
+		if err := store.PruneDaily(time.Now().Add(-DailyRetention)); err != nil {
+			fyne.LogError("prune daily history failed", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recordKey 8字节纳秒时间戳+8字节序号 大端保证按时间排序
+func recordKey(t time.Time, seq uint64) []byte {
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key, uint64(t.UnixNano()))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
@@ -0,0 +1,159 @@
+package history
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/stretchr/testify/assert"
+	"go.etcd.io/bbolt"
+)
+
+func TestStore(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < 3; i++ {
+		assert.Nil(t, store.Add(&rpc.MonitorResult{
+			Source: "local",
+			Time:   start.Add(time.Duration(i) * time.Minute),
+			MonitorResponse: &httpMonitorRpc.MonitorResponse{
+				Url:    "https://www.baidu.com",
+				Result: map[string]string{"": "success", "socks5://127.0.0.1:8000": "timeout"},
+			},
+		}))
+	}
+	assert.Nil(t, store.AddRecords(Record{Time: start, Source: "hk", Url: "https://www.google.com", Result: "success"}))
+
+	urls, err := store.Urls()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"https://www.baidu.com", "https://www.google.com"}, urls)
+
+	records, err := store.Query(Query{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 7)
+
+	records, err = store.Query(Query{Urls: []string{"https://www.baidu.com"}, Proxies: []string{"socks5://127.0.0.1:8000"}})
+	assert.Nil(t, err)
+	assert.Len(t, records, 3)
+	for _, record := range records {
+		assert.Equal(t, "timeout", record.Result)
+	}
+
+	//From包含 To不包含
+	records, err = store.Query(Query{Proxies: []string{""}, From: start.Add(time.Minute), To: start.Add(2 * time.Minute)})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+	assert.True(t, records[0].Time.Equal(start.Add(time.Minute)))
+
+	records, err = store.Query(Query{Sources: []string{"local"}, Proxies: []string{""}, Limit: 2})
+	assert.Nil(t, err)
+	assert.Len(t, records, 2)
+	assert.True(t, records[1].Time.Equal(start.Add(2*time.Minute)))
+
+	deleted, err := store.Prune(start.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, deleted)
+	records, err = store.Query(Query{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 4)
+}
+
+func TestSave(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	bus := rpc.NewEventBus()
+	sub := bus.SubscribeLossless("history")
+	start := time.Date(2022, 6, 1, 0, 0, 0, 0, time.Local)
+	for i := 0; i < 10; i++ {
+		bus.Publish(&rpc.MonitorResult{
+			Source:          "local",
+			Time:            start.Add(time.Duration(i) * time.Minute),
+			MonitorResponse: &httpMonitorRpc.MonitorResponse{Url: "https://www.baidu.com", Result: map[string]string{"": "success"}},
+		})
+	}
+	sub.Unsubscribe()
+	//订阅关闭后写完缓冲中的结果才返回
+	store.Save(sub)
+
+	records, err := store.Query(Query{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 10)
+}
+
+func TestRunPrune(t *testing.T) {
+	store, err := Open(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	now := time.Now()
+	assert.Nil(t, store.AddRecords(
+		Record{Time: now.Add(-48 * time.Hour), Source: "local", Url: "https://www.baidu.com", Result: "success"},
+		Record{Time: now.Add(-time.Hour), Source: "local", Url: "https://www.baidu.com", Result: "success"},
+	))
+
+	//ctx已取消时只清理一次
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	store.RunPrune(ctx, 24*time.Hour, time.Hour)
+	records, err := store.Query(Query{})
+	assert.Nil(t, err)
+	assert.Len(t, records, 1)
+}
+
+func TestDaily(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.db")
+	store, err := Open(path)
+	assert.Nil(t, err)
+
+	day := time.Date(2022, 6, 1, 10, 0, 0, 0, time.Local)
+	check := func(at time.Time, direct, proxied string) {
+		assert.Nil(t, store.AddRecords(
+			Record{Time: at, Source: "local", Url: "https://www.baidu.com", Result: direct},
+			Record{Time: at, Source: "local", Url: "https://www.baidu.com", Proxy: "http://1.2.3.4:80", Result: proxied},
+		))
+	}
+	check(day, "success", "success")
+	check(day.Add(time.Minute), "timeout", "success")
+	check(day.Add(2*time.Minute), "timeout", "timeout")
+	check(day.AddDate(0, 0, 1), "success", "success")
+	want := []Day{
+		{Date: time.Date(2022, 6, 1, 0, 0, 0, 0, time.Local), Checks: 3, Up: 2, Degraded: 1},
+		{Date: time.Date(2022, 6, 2, 0, 0, 0, 0, time.Local), Checks: 1, Up: 1},
+	}
+	daily, err := store.Daily([]string{"https://www.baidu.com", "https://www.google.com"}, day)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string][]Day{"https://www.baidu.com": want}, daily)
+
+	//清理原始记录不影响每日汇总
+	_, err = store.Prune(day.AddDate(0, 0, 2))
+	assert.Nil(t, err)
+	daily, _ = store.Daily([]string{"https://www.baidu.com"}, day)
+	assert.Equal(t, want, daily["https://www.baidu.com"])
+	assert.Nil(t, store.PruneDaily(day.AddDate(0, 0, 1)))
+	daily, _ = store.Daily([]string{"https://www.baidu.com"}, day)
+	assert.Equal(t, want[1:], daily["https://www.baidu.com"])
+	assert.Nil(t, store.Close())
+
+	//旧数据库打开时由原始记录生成每日汇总
+	store, err = Open(path)
+	assert.Nil(t, err)
+	check(day, "success", "success")
+	check(day.Add(time.Minute), "timeout", "success")
+	assert.Nil(t, store.db.Update(func(tx *bbolt.Tx) error {
+		return tx.DeleteBucket([]byte(bucketDaily))
+	}))
+	assert.Nil(t, store.Close())
+	store, err = Open(path)
+	assert.Nil(t, err)
+	defer store.Close()
+	daily, _ = store.Daily([]string{"https://www.baidu.com"}, day)
+	assert.Equal(t, []Day{{Date: want[0].Date, Checks: 2, Up: 2, Degraded: 1}}, daily["https://www.baidu.com"])
+}
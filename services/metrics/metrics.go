@@ -0,0 +1,269 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/status"
+)
+
+// ContentType Prometheus文本格式
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// key 一个服务上 一个url经一个代理的检测 Proxy为空表示直连
+type key struct {
+	Source string
+	Url    string
+	Proxy  string
+}
+
+type series struct {
+	up                  bool
+	lastCheck           time.Time
+	consecutiveFailures int
+	checks              uint64
+	failures            map[result.Category]uint64
+	// interval 最近两次检测的间隔 用于判断url或代理是否已删除
+	interval time.Duration
+}
+
+const (
+	// staleChecks 超过这么多个检测间隔没有结果的序列不再输出
+	staleChecks = 3
+	// staleAfter 只检测过一次 不知道检测间隔时使用
+	staleAfter = 10 * time.Minute
+)
+
+func (s *series) stale(now time.Time) bool {
+	if s.interval > 0 {
+		return now.Sub(s.lastCheck) > staleChecks*s.interval
+	}
+	return now.Sub(s.lastCheck) > staleAfter
+}
+
+// Collector 由监控结果流计算指标 按Prometheus文本格式输出
+type Collector struct {
+	labeler status.Labeler
+	// now 输出时判断序列是否过期 测试时替换
+	now    func() time.Time
+	m      sync.RWMutex
+	series map[key]*series
+}
+
+// NewCollector labeler用于httpmonitor_proxy_info的proxy_name标签 可以为nil
+func NewCollector(labeler status.Labeler) *Collector {
+	return &Collector{labeler: labeler, now: time.Now, series: make(map[key]*series)}
+}
+
+// Observe 记录一次监控结果 每个代理一次检测
+// 同时删除本次结果中没有的代理 以及长时间没有结果的url与代理
+func (collector *Collector) Observe(res *rpc.MonitorResult) {
+	if res == nil || res.MonitorResponse == nil || res.Url == "" {
+		return
+	}
+	t := res.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	collector.m.Lock()
+	defer collector.m.Unlock()
+	for p, msg := range res.Result {
+		k := key{Source: res.Source, Url: res.Url, Proxy: p}
+		s, ok := collector.series[k]
+		if !ok {
+			s = &series{failures: make(map[result.Category]uint64)}
+			collector.series[k] = s
+		}
+
+		r := result.Parse(msg)
+		s.checks++
+		if !s.lastCheck.IsZero() && t.After(s.lastCheck) {
+			s.interval = t.Sub(s.lastCheck)
+		}
+		s.lastCheck = t
+		s.up = r.OK()
+		if s.up {
+			s.consecutiveFailures = 0
+		} else {
+			s.consecutiveFailures++
+			s.failures[r.Category()]++
+		}
+	}
+	for k, s := range collector.series {
+		if _, ok := res.Result[k.Proxy]; !ok && k.Source == res.Source && k.Url == res.Url {
+			delete(collector.series, k)
+		} else if s.stale(t) {
+			delete(collector.series, k)
+		}
+	}
+}
+
+// Run 消费订阅直到取消订阅
+func (collector *Collector) Run(sub *rpc.Subscription) {
+	for res := range sub.C {
+		collector.Observe(res)
+	}
+}
+
+// categoryLabel 标签值使用英文 便于在Grafana中书写查询
+func categoryLabel(category result.Category) string {
+	switch category {
+	case result.CategoryNetwork:
+		return "network"
+	case result.CategorySecurity:
+		return "security"
+	case result.CategoryServer:
+		return "server"
+	case result.CategoryProxy:
+		return "proxy"
+	}
+	return "unknown"
+}
+
+// categories 输出顺序 没有失败的分类也输出0 便于计算速率
+var categories = []result.Category{result.CategoryNetwork, result.CategorySecurity, result.CategoryServer, result.CategoryProxy, result.CategoryUnknown}
+
+// escape 标签值转义反斜杠 双引号与换行
+var escape = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// labels 代理地址隐藏密码 显示名称会被修改 只在httpmonitor_proxy_info中输出
+func (k key) labels() string {
+	return fmt.Sprintf(`source="%s",url="%s",proxy="%s"`,
+		escape.Replace(k.Source), escape.Replace(k.Url), escape.Replace(proxy.Redact(k.Proxy)))
+}
+
+type metric struct {
+	name  string
+	help  string
+	kind  string
+	value func(s *series) []sample
+}
+
+type sample struct {
+	labels string
+	value  string
+}
+
+var metrics = []metric{
+	{"httpmonitor_up", "最近一次检测是否正常 1为正常", "gauge", func(s *series) []sample {
+		if s.up {
+			return []sample{{value: "1"}}
+		}
+		return []sample{{value: "0"}}
+	}},
+	{"httpmonitor_last_check_timestamp_seconds", "最近一次检测的时间", "gauge", func(s *series) []sample {
+		return []sample{{value: strconv.FormatFloat(float64(s.lastCheck.UnixMilli())/1000, 'f', -1, 64)}}
+	}},
+	{"httpmonitor_consecutive_failures", "连续失败次数", "gauge", func(s *series) []sample {
+		return []sample{{value: strconv.Itoa(s.consecutiveFailures)}}
+	}},
+	{"httpmonitor_checks_total", "检测次数", "counter", func(s *series) []sample {
+		return []sample{{value: strconv.FormatUint(s.checks, 10)}}
+	}},
+	{"httpmonitor_failures_total", "按错误分类的失败次数", "counter", func(s *series) []sample {
+		samples := make([]sample, 0, len(categories))
+		for _, category := range categories {
+			samples = append(samples, sample{labels: `,category="` + categoryLabel(category) + `"`, value: strconv.FormatUint(s.failures[category], 10)})
+		}
+		return samples
+	}},
+}
+
+// Write 按Prometheus文本格式输出全部指标 按服务 url 代理排序
+// 监控停止或服务断开后不再有结果 过期的序列不输出 避免一直显示为正常
+func (collector *Collector) Write(w io.Writer) error {
+	collector.m.RLock()
+	defer collector.m.RUnlock()
+
+	now := collector.now()
+	keys := make([]key, 0, len(collector.series))
+	for k, s := range collector.series {
+		if !s.stale(now) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Source != keys[j].Source {
+			return keys[i].Source < keys[j].Source
+		}
+		if keys[i].Url != keys[j].Url {
+			return keys[i].Url < keys[j].Url
+		}
+		return keys[i].Proxy < keys[j].Proxy
+	})
+	labels := make([]string, len(keys))
+	for i, k := range keys {
+		labels[i] = k.labels()
+	}
+
+	writer := bufio.NewWriter(w)
+	for _, m := range metrics {
+		fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", m.name, m.help, m.name, m.kind)
+		for i, k := range keys {
+			for _, s := range m.value(collector.series[k]) {
+				fmt.Fprintf(writer, "%s{%s%s} %s\n", m.name, labels[i], s.labels, s.value)
+			}
+		}
+	}
+
+	//代理显示名称 查询时按proxy关联 修改名称不影响其他指标
+	var proxies []string
+	seen := make(map[string]bool)
+	for _, k := range keys {
+		if !seen[k.Proxy] {
+			seen[k.Proxy] = true
+			proxies = append(proxies, k.Proxy)
+		}
+	}
+	sort.Strings(proxies)
+	fmt.Fprint(writer, "# HELP httpmonitor_proxy_info 代理的显示名称\n# TYPE httpmonitor_proxy_info gauge\n")
+	for _, p := range proxies {
+		fmt.Fprintf(writer, "httpmonitor_proxy_info{proxy=\"%s\",proxy_name=\"%s\"} 1\n", escape.Replace(proxy.Redact(p)), escape.Replace(collector.labeler.Proxy(p)))
+	}
+
+	return writer.Flush()
+}
+
+func (collector *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	w.Header().Set("Content-Type", ContentType)
+	if err := collector.Write(w); err != nil {
+		fyne.LogError("write metrics failed", err)
+	}
+}
+
+// Serve 在addr上提供/metrics 监听失败时立即返回错误 关闭返回的Server即停止
+func Serve(addr string, collector *Collector) (*http.Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", collector)
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && err != http.ErrServerClosed {
+			fyne.LogError("metrics server stopped", err)
+		}
+	}()
+
+	return server, nil
+}
@@ -0,0 +1,273 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/rs/zerolog/log"
+)
+
+const preferenceChannels = "notifyChannels"
+
+// Message 一条通知
+type Message struct {
+	Title string    `json:"title"`
+	Text  string    `json:"text"`
+	Url   string    `json:"url,omitempty"`
+	State string    `json:"state,omitempty"`
+	Time  time.Time `json:"time"`
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Notify(ctx context.Context, msg Message) error
+}
+
+type Kind string
+
+const (
+	KindWebhook  Kind = "webhook"
+	KindSmtp     Kind = "smtp"
+	KindSlack    Kind = "slack"
+	KindDingTalk Kind = "dingtalk"
+	KindFeishu   Kind = "feishu"
+	KindWeCom    Kind = "wecom"
+)
+
+var Kinds = []Kind{KindWebhook, KindSmtp, KindSlack, KindDingTalk, KindFeishu, KindWeCom}
+
+func (kind Kind) String() string {
+	switch kind {
+	case KindWebhook:
+		return "Webhook"
+	case KindSmtp:
+		return "邮件"
+	case KindSlack:
+		return "Slack"
+	case KindDingTalk:
+		return "钉钉"
+	case KindFeishu:
+		return "飞书"
+	case KindWeCom:
+		return "企业微信"
+	}
+	return string(kind)
+}
+
+// EnvPrefix Url Secret Password以此开头时 保存的是环境变量名 发送时才读取
+// 配置明文保存在Preferences中 不希望密钥落盘时使用 如"env:DINGTALK_SECRET"
+const EnvPrefix = "env:"
+
+// Channel 通知渠道配置 邮件使用Smtp相关字段 其他渠道使用Url
+type Channel struct {
+	Name    string
+	Kind    Kind
+	Enabled bool
+	// Url webhook地址
+	Url string
+	// Secret 钉钉 飞书机器人的加签密钥 为空不加签
+	Secret string
+	// Host smtp服务地址 host:port
+	Host     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+// resolve 读取env:开头的环境变量 其他值原样返回
+func resolve(value string) (string, error) {
+	if !strings.HasPrefix(value, EnvPrefix) {
+		return value, nil
+	}
+	name := strings.TrimPrefix(value, EnvPrefix)
+	v, ok := os.LookupEnv(name)
+	if !ok || v == "" {
+		return "", errors.New("环境变量" + name + "未设置")
+	}
+	return v, nil
+}
+
+// resolved 替换Url Secret Password中引用的环境变量
+func (channel Channel) resolved() (Channel, error) {
+	var err error
+	for _, field := range []*string{&channel.Url, &channel.Secret, &channel.Password} {
+		if *field, err = resolve(*field); err != nil {
+			return channel, err
+		}
+	}
+	return channel, nil
+}
+
+// Validate 引用的环境变量未设置时返回错误
+func (channel Channel) Validate() error {
+	channel, err := channel.resolved()
+	if err != nil {
+		return err
+	}
+	if channel.Name == "" {
+		return errors.New("名称不能为空")
+	}
+
+	switch channel.Kind {
+	case KindSmtp:
+		if _, _, err := net.SplitHostPort(channel.Host); err != nil {
+			return errors.New("smtp地址格式错误 eg:smtp.qq.com:25")
+		}
+		if channel.From == "" || len(channel.To) == 0 {
+			return errors.New("发件人与收件人不能为空")
+		}
+	case KindWebhook, KindSlack, KindDingTalk, KindFeishu, KindWeCom:
+		u, err := url.Parse(channel.Url)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("webhook地址格式错误")
+		}
+	default:
+		return errors.New("未知渠道类型:" + string(channel.Kind))
+	}
+
+	return nil
+}
+
+// Notifier 根据配置创建通知渠道
+func (channel Channel) Notifier() (Notifier, error) {
+	if err := channel.Validate(); err != nil {
+		return nil, err
+	}
+	channel, _ = channel.resolved()
+
+	if channel.Kind == KindSmtp {
+		return &SmtpNotifier{Host: channel.Host, Username: channel.Username, Password: channel.Password, From: channel.From, To: channel.To}, nil
+	}
+
+	return &WebhookNotifier{Kind: channel.Kind, Url: channel.Url, Secret: channel.Secret}, nil
+}
+
+// Store 通知渠道配置 明文保存在fyne Preferences中 密钥与密码建议使用EnvPrefix引用环境变量
+type Store struct {
+	prefs fyne.Preferences
+	m     sync.Mutex
+}
+
+func NewStore(prefs fyne.Preferences) *Store {
+	return &Store{prefs: prefs}
+}
+
+func (store *Store) List() []Channel {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.list()
+}
+
+func (store *Store) Get(name string) (Channel, bool) {
+	for _, channel := range store.List() {
+		if channel.Name == name {
+			return channel, true
+		}
+	}
+
+	return Channel{}, false
+}
+
+// Save 新增或按名称覆盖配置
+func (store *Store) Save(channel Channel) error {
+	if err := channel.Validate(); err != nil {
+		return err
+	}
+
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	channels := store.list()
+	replaced := false
+	for i, c := range channels {
+		if c.Name == channel.Name {
+			channels[i] = channel
+			replaced = true
+		}
+	}
+	if !replaced {
+		channels = append(channels, channel)
+	}
+
+	return store.save(channels)
+}
+
+func (store *Store) Delete(name string) error {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	channels := store.list()
+	for i, c := range channels {
+		if c.Name == name {
+			return store.save(append(channels[:i], channels[i+1:]...))
+		}
+	}
+
+	return nil
+}
+
+// Send 并发发送到全部启用的渠道 返回每个失败渠道的错误
+func (store *Store) Send(ctx context.Context, msg Message) map[string]error {
+	var wg sync.WaitGroup
+	var errM sync.Mutex
+	errs := make(map[string]error)
+	for _, channel := range store.List() {
+		if !channel.Enabled {
+			continue
+		}
+		wg.Add(1)
+		go func(channel Channel) {
+			defer wg.Done()
+			if err := Send(ctx, channel, msg); err != nil {
+				log.Error().Caller().Str("channel", channel.Name).Msg(err.Error())
+				errM.Lock()
+				errs[channel.Name] = err
+				errM.Unlock()
+			}
+		}(channel)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// Send 发送到指定渠道 用于测试配置
+func Send(ctx context.Context, channel Channel, msg Message) error {
+	notifier, err := channel.Notifier()
+	if err != nil {
+		return err
+	}
+
+	return notifier.Notify(ctx, msg)
+}
+
+func (store *Store) list() []Channel {
+	var channels []Channel
+	if str := store.prefs.String(preferenceChannels); str != "" {
+		if err := json.Unmarshal([]byte(str), &channels); err != nil {
+			fyne.LogError("load notify channels failed", err)
+		}
+	}
+
+	return channels
+}
+
+func (store *Store) save(channels []Channel) error {
+	data, err := json.Marshal(channels)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceChannels, string(data))
+
+	return nil
+}
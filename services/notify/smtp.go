@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SmtpNotifier 发送邮件 Username为空时不认证
+type SmtpNotifier struct {
+	Host     string
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (notifier *SmtpNotifier) Notify(ctx context.Context, msg Message) error {
+	var auth smtp.Auth
+	if notifier.Username != "" {
+		host, _, _ := net.SplitHostPort(notifier.Host)
+		auth = smtp.PlainAuth("", notifier.Username, notifier.Password, host)
+	}
+
+	//smtp.SendMail不支持context 在单独的goroutine中发送
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(notifier.Host, auth, notifier.From, notifier.To, notifier.message(msg))
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (notifier *SmtpNotifier) message(msg Message) []byte {
+	t := msg.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("From: " + notifier.From + "\r\n")
+	buf.WriteString("To: " + strings.Join(notifier.To, ", ") + "\r\n")
+	buf.WriteString("Subject: " + mime.BEncoding.Encode("utf-8", msg.Title) + "\r\n")
+	buf.WriteString("Date: " + t.Format(time.RFC1123Z) + "\r\n")
+	buf.WriteString("MIME-Version: 1.0\r\n")
+	buf.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	buf.WriteString("Content-Transfer-Encoding: 8bit\r\n\r\n")
+	buf.WriteString(strings.ReplaceAll(msg.Text, "\n", "\r\n"))
+	buf.WriteString("\r\n")
+
+	return buf.Bytes()
+}
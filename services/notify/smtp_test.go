@@ -0,0 +1,77 @@
+package notify
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// smtpServer 只支持发送一封邮件的smtp服务 返回地址和收到的邮件内容
+func smtpServer(t *testing.T) (string, chan string) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = lis.Close()
+	})
+
+	mails := make(chan string, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		_ = text.PrintfLine("220 localhost ESMTP")
+		var envelope []string
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			switch cmd := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); cmd {
+			case "EHLO", "HELO":
+				_ = text.PrintfLine("250 localhost")
+			case "MAIL", "RCPT":
+				envelope = append(envelope, line)
+				_ = text.PrintfLine("250 OK")
+			case "DATA":
+				_ = text.PrintfLine("354 End data with <CR><LF>.<CR><LF>")
+				data, err := text.ReadDotLines()
+				if err != nil {
+					return
+				}
+				mails <- strings.Join(envelope, "\n") + "\n\n" + strings.Join(data, "\n")
+				_ = text.PrintfLine("250 OK")
+			case "QUIT":
+				_ = text.PrintfLine("221 Bye")
+				return
+			default:
+				_ = text.PrintfLine("502 not implemented")
+			}
+		}
+	}()
+
+	return lis.Addr().String(), mails
+}
+
+func TestSmtpNotifier(t *testing.T) {
+	address, mails := smtpServer(t)
+
+	channel := Channel{Name: "mail", Kind: KindSmtp, Host: address, From: "monitor@example.com", To: []string{"ops@example.com", "dev@example.com"}}
+	assert.Nil(t, Send(context.Background(), channel, testMessage))
+
+	mail := <-mails
+	assert.Contains(t, mail, "MAIL FROM:<monitor@example.com>")
+	assert.Contains(t, mail, "RCPT TO:<dev@example.com>")
+	assert.Contains(t, mail, "To: ops@example.com, dev@example.com")
+	assert.Contains(t, mail, "Subject: =?utf-8?b?")
+	assert.Contains(t, mail, "连续失败3次 超时")
+
+	assert.NotNil(t, Channel{Name: "mail", Kind: KindSmtp, Host: "smtp.example.com"}.Validate())
+}
@@ -0,0 +1,134 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// HttpClient webhook请求使用的客户端
+var HttpClient = &http.Client{Timeout: 10 * time.Second}
+
+// WebhookNotifier 通过http POST json发送 Kind决定消息格式
+type WebhookNotifier struct {
+	Kind   Kind
+	Url    string
+	Secret string
+}
+
+func (notifier *WebhookNotifier) Notify(ctx context.Context, msg Message) error {
+	target := notifier.Url
+	content := msg.Title + "\n" + msg.Text
+
+	var payload interface{}
+	switch notifier.Kind {
+	case KindSlack:
+		payload = map[string]string{"text": "*" + msg.Title + "*\n" + msg.Text}
+	case KindDingTalk:
+		payload = map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": content}}
+		if notifier.Secret != "" {
+			target = dingTalkSign(target, notifier.Secret, time.Now())
+		}
+	case KindFeishu:
+		body := map[string]interface{}{"msg_type": "text", "content": map[string]string{"text": content}}
+		if notifier.Secret != "" {
+			timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+			body["timestamp"] = timestamp
+			body["sign"] = feishuSign(timestamp, notifier.Secret)
+		}
+		payload = body
+	case KindWeCom:
+		payload = map[string]interface{}{"msgtype": "text", "text": map[string]string{"content": content}}
+	default:
+		payload = msg
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	res, err := HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+	if err != nil {
+		return err
+	}
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("%s %s", res.Status, body)
+	}
+
+	return checkRobotResponse(notifier.Kind, body)
+}
+
+// checkRobotResponse 钉钉 飞书 企业微信出错时http状态码仍为200 需要检查返回的错误码
+func checkRobotResponse(kind Kind, body []byte) error {
+	var res struct {
+		ErrCode *int   `json:"errcode"`
+		ErrMsg  string `json:"errmsg"`
+		Code    *int   `json:"code"`
+		Msg     string `json:"msg"`
+	}
+
+	switch kind {
+	case KindDingTalk, KindWeCom:
+		if err := json.Unmarshal(body, &res); err != nil {
+			return errors.New("返回格式错误:" + string(body))
+		}
+		if res.ErrCode != nil && *res.ErrCode != 0 {
+			return fmt.Errorf("errcode:%d %s", *res.ErrCode, res.ErrMsg)
+		}
+	case KindFeishu:
+		if err := json.Unmarshal(body, &res); err != nil {
+			return errors.New("返回格式错误:" + string(body))
+		}
+		if res.Code != nil && *res.Code != 0 {
+			return fmt.Errorf("code:%d %s", *res.Code, res.Msg)
+		}
+	}
+
+	return nil
+}
+
+// dingTalkSign 钉钉加签 在url上添加timestamp与sign参数
+func dingTalkSign(target, secret string, now time.Time) string {
+	timestamp := strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "\n" + secret))
+	sign := base64.StdEncoding.EncodeToString(mac.Sum(nil))
+
+	u, err := url.Parse(target)
+	if err != nil {
+		return target
+	}
+	query := u.Query()
+	query.Set("timestamp", timestamp)
+	query.Set("sign", sign)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
+
+// feishuSign 飞书加签 以timestamp+"\n"+secret为key对空数据签名
+func feishuSign(timestamp, secret string) string {
+	mac := hmac.New(sha256.New, []byte(timestamp+"\n"+secret))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
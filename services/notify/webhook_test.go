@@ -0,0 +1,128 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+// robotServer 记录收到的请求 返回response
+func robotServer(t *testing.T, response string) (*httptest.Server, chan *http.Request, chan map[string]interface{}) {
+	requests := make(chan *http.Request, 10)
+	bodies := make(chan map[string]interface{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body := make(map[string]interface{})
+		assert.Nil(t, json.Unmarshal(data, &body))
+		requests <- r
+		bodies <- body
+		_, _ = w.Write([]byte(response))
+	}))
+	t.Cleanup(server.Close)
+
+	return server, requests, bodies
+}
+
+var testMessage = Message{Title: "https://www.google.com 告警中", Text: "连续失败3次 超时", Url: "https://www.google.com", State: "告警中", Time: time.Now()}
+
+func TestWebhookNotifier(t *testing.T) {
+	server, requests, bodies := robotServer(t, `{"errcode":0,"errmsg":"ok","code":0}`)
+	ctx := context.Background()
+
+	assert.Nil(t, Send(ctx, Channel{Name: "webhook", Kind: KindWebhook, Url: server.URL}, testMessage))
+	assert.Equal(t, "application/json; charset=utf-8", (<-requests).Header.Get("Content-Type"))
+	body := <-bodies
+	assert.Equal(t, testMessage.Title, body["title"])
+	assert.Equal(t, testMessage.Url, body["url"])
+
+	assert.Nil(t, Send(ctx, Channel{Name: "slack", Kind: KindSlack, Url: server.URL}, testMessage))
+	<-requests
+	assert.Equal(t, "*https://www.google.com 告警中*\n连续失败3次 超时", (<-bodies)["text"])
+
+	assert.Nil(t, Send(ctx, Channel{Name: "dingtalk", Kind: KindDingTalk, Url: server.URL + "/robot/send?access_token=abc", Secret: "SEC123"}, testMessage))
+	query := (<-requests).URL.Query()
+	assert.Equal(t, "abc", query.Get("access_token"))
+	assert.NotEmpty(t, query.Get("timestamp"))
+	assert.NotEmpty(t, query.Get("sign"))
+	body = <-bodies
+	assert.Equal(t, "text", body["msgtype"])
+	assert.Equal(t, testMessage.Title+"\n"+testMessage.Text, body["text"].(map[string]interface{})["content"])
+
+	assert.Nil(t, Send(ctx, Channel{Name: "feishu", Kind: KindFeishu, Url: server.URL, Secret: "SEC123"}, testMessage))
+	<-requests
+	body = <-bodies
+	assert.Equal(t, "text", body["msg_type"])
+	assert.Equal(t, feishuSign(body["timestamp"].(string), "SEC123"), body["sign"])
+
+	assert.Nil(t, Send(ctx, Channel{Name: "wecom", Kind: KindWeCom, Url: server.URL}, testMessage))
+	<-requests
+	assert.Equal(t, "text", (<-bodies)["msgtype"])
+}
+
+func TestWebhookNotifierError(t *testing.T) {
+	//机器人出错时http状态码仍为200
+	server, _, _ := robotServer(t, `{"errcode":310000,"errmsg":"sign not match"}`)
+	err := Send(context.Background(), Channel{Name: "dingtalk", Kind: KindDingTalk, Url: server.URL}, testMessage)
+	assert.EqualError(t, err, "errcode:310000 sign not match")
+
+	server, _, _ = robotServer(t, `{"code":19021,"msg":"sign match fail or timestamp is not within one hour from current time"}`)
+	err = Send(context.Background(), Channel{Name: "feishu", Kind: KindFeishu, Url: server.URL}, testMessage)
+	assert.Contains(t, err.Error(), "code:19021")
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	err = Send(context.Background(), Channel{Name: "slack", Kind: KindSlack, Url: failing.URL}, testMessage)
+	assert.Contains(t, err.Error(), "403")
+
+	assert.NotNil(t, Channel{Name: "bad", Kind: KindWeCom, Url: "ftp://example.com"}.Validate())
+}
+
+func TestDingTalkSign(t *testing.T) {
+	signed := dingTalkSign("https://oapi.dingtalk.com/robot/send?access_token=abc", "SEC123", time.Unix(1, 0))
+	u, err := url.Parse(signed)
+	assert.Nil(t, err)
+	assert.Equal(t, "1000", u.Query().Get("timestamp"))
+	assert.Equal(t, "BfLuc2W0iTDBvocRCxElXGq877vfrq52JtrlU9IhXbg=", u.Query().Get("sign"))
+}
+
+func TestStoreSend(t *testing.T) {
+	server, requests, _ := robotServer(t, `{}`)
+	store := NewStore(test.NewApp().Preferences())
+	assert.Nil(t, store.Save(Channel{Name: "on", Kind: KindWebhook, Url: server.URL, Enabled: true}))
+	assert.Nil(t, store.Save(Channel{Name: "off", Kind: KindWebhook, Url: server.URL}))
+	assert.Nil(t, store.Save(Channel{Name: "down", Kind: KindWebhook, Url: "http://127.0.0.1:1", Enabled: true}))
+	assert.Len(t, store.List(), 3)
+
+	errs := store.Send(context.Background(), testMessage)
+	assert.Len(t, errs, 1)
+	assert.NotNil(t, errs["down"])
+	assert.Len(t, requests, 1)
+
+	assert.Nil(t, store.Delete("down"))
+	assert.Empty(t, store.Send(context.Background(), testMessage))
+}
+
+func TestChannelEnv(t *testing.T) {
+	server, requests, _ := robotServer(t, `{}`)
+	channel := Channel{Name: "env", Kind: KindWebhook, Url: EnvPrefix + "TEST_NOTIFY_URL", Enabled: true}
+	assert.NotNil(t, channel.Validate())
+
+	t.Setenv("TEST_NOTIFY_URL", server.URL)
+	store := NewStore(test.NewApp().Preferences())
+	assert.Nil(t, store.Save(channel))
+	//保存的是环境变量名
+	saved, _ := store.Get("env")
+	assert.Equal(t, EnvPrefix+"TEST_NOTIFY_URL", saved.Url)
+	assert.Empty(t, store.Send(context.Background(), testMessage))
+	assert.Len(t, requests, 1)
+}
@@ -0,0 +1,81 @@
+package probe
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+)
+
+// DefaultTimeout 与httpMonitor服务一致
+const DefaultTimeout = 10 * time.Second
+
+// Check 经proxy访问url 返回与httpMonitor服务相同格式的结果
+// 成功为"success" 请求失败为错误信息 状态码大于500为状态如"502 Bad Gateway"
+func Check(ctx context.Context, rawUrl, proxy string, timeout time.Duration) string {
+	if err := check(ctx, rawUrl, proxy, timeout); err != nil {
+		return err.Error()
+	}
+	return "success"
+}
+
+func check(ctx context.Context, rawUrl, proxy string, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, rawUrl, nil)
+	if err != nil {
+		return err
+	}
+
+	transport := &http.Transport{DisableKeepAlives: true}
+	defer transport.CloseIdleConnections()
+	if proxy != "" {
+		proxyUrl, err := url.Parse(proxy)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = http.ProxyURL(proxyUrl)
+	}
+	client := &http.Client{Transport: transport, Timeout: timeout}
+
+	res, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+
+	if res.StatusCode > 500 {
+		return errors.New(res.Status)
+	}
+
+	return nil
+}
+
+// CheckAll 并发检测直连与全部代理 key为代理 直连为空字符串
+func CheckAll(ctx context.Context, rawUrl string, proxies []string, timeout time.Duration) *httpMonitorRpc.MonitorResponse {
+	proxies = append([]string{""}, proxies...)
+	result := make(map[string]string, len(proxies))
+
+	var m sync.Mutex
+	var wg sync.WaitGroup
+	wg.Add(len(proxies))
+	for _, proxy := range proxies {
+		go func(proxy string) {
+			defer wg.Done()
+			msg := Check(ctx, rawUrl, proxy, timeout)
+			m.Lock()
+			result[proxy] = msg
+			m.Unlock()
+		}(proxy)
+	}
+	wg.Wait()
+
+	return &httpMonitorRpc.MonitorResponse{Url: rawUrl, Result: result}
+}
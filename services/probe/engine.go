@@ -0,0 +1,313 @@
+package probe
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+)
+
+const (
+	preferenceUrls    = "probeUrls"
+	preferenceProxies = "probeProxies"
+)
+
+// DefaultConcurrency 同时检测的url数量
+const DefaultConcurrency = 16
+
+// Engine 内置检测引擎 不依赖httpMonitor服务 按每个url的间隔直接发起http检测
+// prefs不为nil时地址与代理保存在fyne Preferences中
+type Engine struct {
+	// SourceName 服务名称 默认local
+	SourceName string
+	// Timeout 单次检测超时 默认DefaultTimeout
+	Timeout time.Duration
+	// Concurrency 同时检测的url数量 默认DefaultConcurrency
+	Concurrency int
+
+	prefs    fyne.Preferences
+	m        sync.RWMutex
+	urls     map[string]int32
+	proxies  []string
+	stopChan chan struct{}
+}
+
+var _ rpc.MonitorBackend = (*Engine)(nil)
+
+func NewEngine(prefs fyne.Preferences) *Engine {
+	engine := &Engine{prefs: prefs, urls: make(map[string]int32)}
+	if prefs != nil {
+		if str := prefs.String(preferenceUrls); str != "" {
+			if err := json.Unmarshal([]byte(str), &engine.urls); err != nil {
+				fyne.LogError("load probe urls failed", err)
+			}
+		}
+		if str := prefs.String(preferenceProxies); str != "" {
+			if err := json.Unmarshal([]byte(str), &engine.proxies); err != nil {
+				fyne.LogError("load probe proxies failed", err)
+			}
+		}
+	}
+
+	return engine
+}
+
+func (engine *Engine) Name() string {
+	if engine.SourceName == "" {
+		return "local"
+	}
+	return engine.SourceName
+}
+
+func (engine *Engine) ListUrl() ([]string, error) {
+	engine.m.RLock()
+	defer engine.m.RUnlock()
+
+	urls := make([]string, 0, len(engine.urls))
+	for url := range engine.urls {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	return urls, nil
+}
+
+func (engine *Engine) ListUrlInterval() (map[string]int32, error) {
+	engine.m.RLock()
+	defer engine.m.RUnlock()
+
+	urlInterval := make(map[string]int32, len(engine.urls))
+	for url, interval := range engine.urls {
+		urlInterval[url] = interval
+	}
+
+	return urlInterval, nil
+}
+
+func (engine *Engine) SetUrl(url string, interval int32) error {
+	if err := rpc.ValidateUrl(url); err != nil {
+		return err
+	}
+	if interval <= 0 {
+		return errors.New("间隔时间必须大于0")
+	}
+
+	engine.m.Lock()
+	defer engine.m.Unlock()
+	engine.urls[url] = interval
+
+	return engine.save()
+}
+
+func (engine *Engine) DeleteUrl(url string) error {
+	engine.m.Lock()
+	defer engine.m.Unlock()
+	delete(engine.urls, url)
+
+	return engine.save()
+}
+
+func (engine *Engine) ListProxy() ([]string, error) {
+	engine.m.RLock()
+	defer engine.m.RUnlock()
+
+	return append([]string(nil), engine.proxies...), nil
+}
+
+func (engine *Engine) SetProxy(proxy string) error {
+	if proxy == "" {
+		return errors.New("代理不能为空")
+	}
+
+	engine.m.Lock()
+	defer engine.m.Unlock()
+	for _, v := range engine.proxies {
+		if v == proxy {
+			return nil
+		}
+	}
+	engine.proxies = append(engine.proxies, proxy)
+
+	return engine.save()
+}
+
+func (engine *Engine) DeleteProxy(proxy string) error {
+	engine.m.Lock()
+	defer engine.m.Unlock()
+	for i, v := range engine.proxies {
+		if v == proxy {
+			engine.proxies = append(engine.proxies[:i], engine.proxies[i+1:]...)
+			break
+		}
+	}
+
+	return engine.save()
+}
+
+// save 调用方持有写锁
+func (engine *Engine) save() error {
+	if engine.prefs == nil {
+		return nil
+	}
+	urls, err := json.Marshal(engine.urls)
+	if err != nil {
+		return err
+	}
+	proxies, err := json.Marshal(engine.proxies)
+	if err != nil {
+		return err
+	}
+	engine.prefs.SetString(preferenceUrls, string(urls))
+	engine.prefs.SetString(preferenceProxies, string(proxies))
+
+	return nil
+}
+
+// stream 检测结果流 StopMonitor后返回io.EOF
+type stream struct {
+	ctx      context.Context
+	results  chan *httpMonitorRpc.MonitorResponse
+	stopChan chan struct{}
+}
+
+func (stream *stream) Recv() (*httpMonitorRpc.MonitorResponse, error) {
+	//停止后不再返回缓冲中的结果
+	select {
+	case <-stream.stopChan:
+		return nil, io.EOF
+	default:
+	}
+
+	select {
+	case res := <-stream.results:
+		return res, nil
+	case <-stream.stopChan:
+		return nil, io.EOF
+	case <-stream.ctx.Done():
+		return nil, stream.ctx.Err()
+	}
+}
+
+// OpenMonitor 启动检测 同一url上一次检测未结束时跳过本次
+func (engine *Engine) OpenMonitor(ctx context.Context) (rpc.MonitorStream, error) {
+	engine.m.Lock()
+	if engine.stopChan != nil {
+		engine.m.Unlock()
+		return nil, errors.New("监控已启动")
+	}
+	stopChan := make(chan struct{})
+	engine.stopChan = stopChan
+	engine.m.Unlock()
+
+	//检测请求随停止或ctx取消而取消
+	checkCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-stopChan:
+		case <-checkCtx.Done():
+		}
+		cancel()
+	}()
+
+	s := &stream{ctx: ctx, results: make(chan *httpMonitorRpc.MonitorResponse, 10), stopChan: stopChan}
+	go engine.schedule(checkCtx, s)
+
+	return s, nil
+}
+
+func (engine *Engine) schedule(ctx context.Context, s *stream) {
+	defer func() {
+		engine.m.Lock()
+		if engine.stopChan == s.stopChan {
+			engine.stopChan = nil
+		}
+		engine.m.Unlock()
+	}()
+
+	concurrency := engine.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+	sem := make(chan struct{}, concurrency)
+
+	var inflight sync.Map
+	lastCheck := make(map[string]time.Time)
+	ticker := time.NewTicker(100 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, url := range engine.dueUrls(lastCheck, now) {
+				if _, running := inflight.LoadOrStore(url, true); running {
+					continue
+				}
+				lastCheck[url] = now
+				select {
+				case sem <- struct{}{}:
+				case <-ctx.Done():
+					return
+				}
+				go func(url string) {
+					defer func() {
+						<-sem
+						inflight.Delete(url)
+					}()
+					res := CheckAll(ctx, url, engine.proxyList(), engine.Timeout)
+					if ctx.Err() != nil {
+						return
+					}
+					select {
+					case s.results <- res:
+					case <-ctx.Done():
+					}
+				}(url)
+			}
+		}
+	}
+}
+
+func (engine *Engine) StopMonitor() error {
+	engine.m.Lock()
+	defer engine.m.Unlock()
+	if engine.stopChan != nil {
+		close(engine.stopChan)
+		engine.stopChan = nil
+	}
+
+	return nil
+}
+
+// dueUrls 返回到达检测时间的url 已删除的url不再检测
+func (engine *Engine) dueUrls(lastCheck map[string]time.Time, now time.Time) []string {
+	engine.m.RLock()
+	defer engine.m.RUnlock()
+
+	var due []string
+	for url, interval := range engine.urls {
+		if last, ok := lastCheck[url]; !ok || now.Sub(last) >= time.Duration(interval)*time.Millisecond {
+			due = append(due, url)
+		}
+	}
+	for url := range lastCheck {
+		if _, ok := engine.urls[url]; !ok {
+			delete(lastCheck, url)
+		}
+	}
+
+	return due
+}
+
+func (engine *Engine) proxyList() []string {
+	engine.m.RLock()
+	defer engine.m.RUnlock()
+	return append([]string(nil), engine.proxies...)
+}
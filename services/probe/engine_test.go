@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer ok.Close()
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer bad.Close()
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+	//http代理收到完整url 转发到ok
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.RequestURI, "http://"))
+		_, _ = w.Write([]byte("proxied"))
+	}))
+	defer proxy.Close()
+
+	ctx := context.Background()
+	assert.Equal(t, "success", Check(ctx, ok.URL, "", time.Second))
+	assert.Equal(t, "502 Bad Gateway", Check(ctx, bad.URL, "", time.Second))
+	assert.Equal(t, result.StatusTimeout, result.Parse(Check(ctx, slow.URL, "", 50*time.Millisecond)).Status)
+	assert.Equal(t, "success", Check(ctx, bad.URL, proxy.URL, time.Second))
+
+	res := CheckAll(ctx, bad.URL, []string{proxy.URL}, time.Second)
+	assert.Equal(t, map[string]string{"": "502 Bad Gateway", proxy.URL: "success"}, res.Result)
+}
+
+func TestEngine(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+
+	prefs := test.NewApp().Preferences()
+	engine := NewEngine(prefs)
+	assert.Equal(t, "local", engine.Name())
+	assert.NotNil(t, engine.SetUrl("ftp://example.com", 100))
+	assert.Nil(t, engine.SetUrl(server.URL, 100))
+
+	//地址保存在Preferences中
+	urls, _ := NewEngine(prefs).ListUrlInterval()
+	assert.Equal(t, map[string]int32{server.URL: 100}, urls)
+
+	stream, err := engine.OpenMonitor(context.Background())
+	assert.Nil(t, err)
+	_, err = engine.OpenMonitor(context.Background())
+	assert.NotNil(t, err)
+
+	for i := 0; i < 2; i++ {
+		res, err := stream.Recv()
+		assert.Nil(t, err)
+		assert.Equal(t, server.URL, res.Url)
+		assert.Equal(t, map[string]string{"": "success"}, res.Result)
+	}
+
+	assert.Nil(t, engine.StopMonitor())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	//停止后可以重新启动
+	stream, err = engine.OpenMonitor(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, engine.StopMonitor())
+}
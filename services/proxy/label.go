@@ -0,0 +1,174 @@
+package proxy
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+const preferenceLabels = "proxyLabels"
+
+// Label 代理的显示名称 地区与分组 只保存在客户端
+type Label struct {
+	Proxy  string
+	Name   string
+	Region string
+	Group  string
+}
+
+// Empty 没有任何信息
+func (label Label) Empty() bool {
+	return label.Name == "" && label.Region == "" && label.Group == ""
+}
+
+// Display eg:香港1(HK) 没有名称时显示隐藏密码的代理地址
+func (label Label) Display() string {
+	text := label.Name
+	if text == "" {
+		text = Redact(label.Proxy)
+	}
+	if label.Region != "" {
+		text += "(" + label.Region + ")"
+	}
+	return text
+}
+
+// LabelStore 代理标签 保存在fyne Preferences中
+type LabelStore struct {
+	prefs fyne.Preferences
+	m     sync.Mutex
+	// cache 按代理索引 Name在监控刷新时频繁调用
+	cache map[string]Label
+}
+
+func NewLabelStore(prefs fyne.Preferences) *LabelStore {
+	return &LabelStore{prefs: prefs}
+}
+
+func (store *LabelStore) List() []Label {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	labels := make([]Label, 0, len(store.index()))
+	for _, label := range store.index() {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Proxy < labels[j].Proxy
+	})
+
+	return labels
+}
+
+// Get 没有设置过时返回只有Proxy的Label
+func (store *LabelStore) Get(proxy string) Label {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	if label, ok := store.index()[proxy]; ok {
+		return label
+	}
+	return Label{Proxy: proxy}
+}
+
+// Name 代理的显示名称 用于监控矩阵 通知等
+func (store *LabelStore) Name(proxy string) string {
+	return store.Get(proxy).Display()
+}
+
+// Groups 全部分组名
+func (store *LabelStore) Groups() []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, label := range store.List() {
+		if label.Group != "" && !seen[label.Group] {
+			seen[label.Group] = true
+			groups = append(groups, label.Group)
+		}
+	}
+	sort.Strings(groups)
+
+	return groups
+}
+
+// Save 新增或按代理覆盖 信息都为空时删除
+func (store *LabelStore) Save(label Label) error {
+	if label.Proxy == "" {
+		return errors.New("代理不能为空")
+	}
+	label.Name = strings.TrimSpace(label.Name)
+	label.Region = strings.TrimSpace(label.Region)
+	label.Group = strings.TrimSpace(label.Group)
+
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	index := store.copyIndex()
+	if label.Empty() {
+		delete(index, label.Proxy)
+	} else {
+		index[label.Proxy] = label
+	}
+
+	return store.save(index)
+}
+
+func (store *LabelStore) Delete(proxy string) error {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	index := store.copyIndex()
+	delete(index, proxy)
+
+	return store.save(index)
+}
+
+func (store *LabelStore) index() map[string]Label {
+	if store.cache != nil {
+		return store.cache
+	}
+
+	var labels []Label
+	if str := store.prefs.String(preferenceLabels); str != "" {
+		if err := json.Unmarshal([]byte(str), &labels); err != nil {
+			fyne.LogError("load proxy labels failed", err)
+		}
+	}
+	store.cache = make(map[string]Label, len(labels))
+	for _, label := range labels {
+		store.cache[label.Proxy] = label
+	}
+
+	return store.cache
+}
+
+func (store *LabelStore) copyIndex() map[string]Label {
+	index := make(map[string]Label, len(store.index()))
+	for proxy, label := range store.index() {
+		index[proxy] = label
+	}
+	return index
+}
+
+func (store *LabelStore) save(index map[string]Label) error {
+	labels := make([]Label, 0, len(index))
+	for _, label := range index {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		return labels[i].Proxy < labels[j].Proxy
+	})
+
+	data, err := json.Marshal(labels)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceLabels, string(data))
+	store.cache = index
+
+	return nil
+}
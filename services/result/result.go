@@ -0,0 +1,188 @@
+package result
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Status 检测结果类型
+type Status int
+
+const (
+	StatusUnknown Status = iota
+	StatusOK
+	StatusTimeout
+	StatusDNS
+	StatusRefused
+	StatusTLS
+	StatusHTTP
+	StatusProxy
+)
+
+func (status Status) String() string {
+	switch status {
+	case StatusOK:
+		return "正常"
+	case StatusTimeout:
+		return "超时"
+	case StatusDNS:
+		return "域名解析失败"
+	case StatusRefused:
+		return "连接被拒绝"
+	case StatusTLS:
+		return "TLS错误"
+	case StatusHTTP:
+		return "HTTP状态异常"
+	case StatusProxy:
+		return "代理错误"
+	}
+	return "未知错误"
+}
+
+// Category 错误分类 用于告警与统计
+type Category int
+
+const (
+	CategoryNone Category = iota
+	CategoryNetwork
+	CategorySecurity
+	CategoryServer
+	CategoryProxy
+	CategoryUnknown
+)
+
+func (category Category) String() string {
+	switch category {
+	case CategoryNone:
+		return "无"
+	case CategoryNetwork:
+		return "网络"
+	case CategorySecurity:
+		return "证书"
+	case CategoryServer:
+		return "服务端"
+	case CategoryProxy:
+		return "代理"
+	}
+	return "未知"
+}
+
+func (status Status) Category() Category {
+	switch status {
+	case StatusOK:
+		return CategoryNone
+	case StatusTimeout, StatusDNS, StatusRefused:
+		return CategoryNetwork
+	case StatusTLS:
+		return CategorySecurity
+	case StatusHTTP:
+		return CategoryServer
+	case StatusProxy:
+		return CategoryProxy
+	}
+	return CategoryUnknown
+}
+
+// Result 解析后的检测结果
+type Result struct {
+	Status Status
+	// Code HTTP状态码 仅StatusHTTP有效
+	Code int
+	// Message httpMonitor返回的原始信息
+	Message string
+}
+
+func (r Result) OK() bool {
+	return r.Status == StatusOK
+}
+
+func (r Result) Category() Category {
+	return r.Status.Category()
+}
+
+// String 简短描述 HTTP状态异常时带状态码
+func (r Result) String() string {
+	if r.Status == StatusHTTP {
+		return "HTTP " + strconv.Itoa(r.Code)
+	}
+	return r.Status.String()
+}
+
+// httpStatusRe httpMonitor在状态码大于500时返回res.Status 如"502 Bad Gateway"
+var httpStatusRe = regexp.MustCompile(`^([1-5][0-9]{2})(\s|$)`)
+
+// patterns 按顺序匹配 代理错误中常包含被拒绝或超时 必须先匹配
+var patterns = []struct {
+	status   Status
+	keywords []string
+}{
+	{StatusProxy, []string{"proxyconnect", "socks connect", "Proxy Authentication Required", "unknown scheme"}},
+	{StatusTimeout, []string{"Client.Timeout exceeded", "context deadline exceeded", "i/o timeout", "timeout"}},
+	{StatusDNS, []string{"no such host", "server misbehaving", "lookup "}},
+	{StatusRefused, []string{"connection refused", "actively refused"}},
+	{StatusTLS, []string{"x509:", "tls:", "certificate"}},
+}
+
+// Parse 解析httpMonitor返回的结果信息 成功为"success" 失败为go的错误信息
+func Parse(message string) Result {
+	message = strings.TrimSpace(message)
+	if message == "success" {
+		return Result{Status: StatusOK, Message: message}
+	}
+
+	if match := httpStatusRe.FindStringSubmatch(message); match != nil {
+		code, _ := strconv.Atoi(match[1])
+		return Result{Status: StatusHTTP, Code: code, Message: message}
+	}
+
+	for _, pattern := range patterns {
+		for _, keyword := range pattern.keywords {
+			if strings.Contains(message, keyword) {
+				return Result{Status: pattern.status, Message: message}
+			}
+		}
+	}
+
+	return Result{Status: StatusUnknown, Message: message}
+}
+
+// Stats 按类型统计检测结果
+type Stats struct {
+	Total  int
+	counts map[Status]int
+}
+
+func (stats *Stats) Add(r Result) {
+	if stats.counts == nil {
+		stats.counts = make(map[Status]int)
+	}
+	stats.Total++
+	stats.counts[r.Status]++
+}
+
+func (stats *Stats) Count(status Status) int {
+	return stats.counts[status]
+}
+
+// CategoryCount 某一分类的失败次数
+func (stats *Stats) CategoryCount(category Category) (count int) {
+	for status, n := range stats.counts {
+		if status.Category() == category {
+			count += n
+		}
+	}
+	return
+}
+
+func (stats *Stats) Failed() int {
+	return stats.Total - stats.counts[StatusOK]
+}
+
+// Availability 成功比例 没有结果时为1
+func (stats *Stats) Availability() float64 {
+	if stats.Total == 0 {
+		return 1
+	}
+	return float64(stats.counts[StatusOK]) / float64(stats.Total)
+}
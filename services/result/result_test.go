@@ -0,0 +1,47 @@
+package result
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	cases := map[string]Status{
+		"success": StatusOK,
+		`Get "https://www.google.com": context deadline exceeded (Client.Timeout exceeded while awaiting headers)`: StatusTimeout,
+		`Get "https://www.google.com": dial tcp 142.250.4.100:443: i/o timeout`:                                    StatusTimeout,
+		`Get "https://example.invalid": dial tcp: lookup example.invalid: no such host`:                            StatusDNS,
+		`Get "http://127.0.0.1:9999": dial tcp 127.0.0.1:9999: connect: connection refused`:                        StatusRefused,
+		`Get "https://self-signed.badssl.com": x509: certificate signed by unknown authority`:                      StatusTLS,
+		`Get "https://www.google.com": proxyconnect tcp: dial tcp 127.0.0.1:8000: connect: connection refused`:     StatusProxy,
+		`Get "https://www.google.com": socks connect tcp 127.0.0.1:8000->www.google.com:443: i/o timeout`:          StatusProxy,
+		`Get "https://proxy.example.invalid": dial tcp: lookup proxy.example.invalid: no such host`:                StatusDNS,
+		"502 Bad Gateway": StatusHTTP,
+		"EOF":             StatusUnknown,
+	}
+	for message, status := range cases {
+		assert.Equal(t, status, Parse(message).Status, message)
+	}
+
+	r := Parse("503 Service Unavailable")
+	assert.Equal(t, 503, r.Code)
+	assert.Equal(t, "HTTP 503", r.String())
+	assert.Equal(t, CategoryServer, r.Category())
+	assert.False(t, r.OK())
+	assert.True(t, Parse("success").OK())
+}
+
+func TestStats(t *testing.T) {
+	var stats Stats
+	assert.Equal(t, float64(1), stats.Availability())
+
+	for _, message := range []string{"success", "success", "success", "502 Bad Gateway", "dial tcp: lookup a: no such host"} {
+		stats.Add(Parse(message))
+	}
+	assert.Equal(t, 5, stats.Total)
+	assert.Equal(t, 2, stats.Failed())
+	assert.Equal(t, 1, stats.Count(StatusHTTP))
+	assert.Equal(t, 1, stats.CategoryCount(CategoryNetwork))
+	assert.Equal(t, 0.6, stats.Availability())
+}
@@ -0,0 +1,163 @@
+package rpc
+
+import (
+	"errors"
+	"sync"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/rs/zerolog/log"
+)
+
+// Aggregator 同时监控多个httpMonitor服务 每个服务一个Session 结果按来源标记后发布到同一个EventBus
+// 单个服务启动失败或断开不影响其他服务
+type Aggregator struct {
+	sources   func() []MonitorBackend
+	stateText binding.String
+
+	m        sync.Mutex
+	sessions map[string]*Session
+}
+
+// NewAggregator sources返回全部可监控的服务
+func NewAggregator(sources func() []MonitorBackend) *Aggregator {
+	aggregator := &Aggregator{
+		sources:   sources,
+		stateText: binding.NewString(),
+		sessions:  make(map[string]*Session),
+	}
+	_ = aggregator.stateText.Set(SessionIdle.String())
+
+	return aggregator
+}
+
+// Sources 全部可监控的服务名称
+func (aggregator *Aggregator) Sources() []string {
+	var names []string
+	for _, backend := range aggregator.sources() {
+		names = append(names, backend.Name())
+	}
+
+	return names
+}
+
+// Session 服务对应的监控会话 服务不存在时返回nil
+func (aggregator *Aggregator) Session(name string) *Session {
+	for _, backend := range aggregator.sources() {
+		if backend.Name() == name {
+			return aggregator.session(backend)
+		}
+	}
+
+	return nil
+}
+
+// Running 正在监控的服务名称
+func (aggregator *Aggregator) Running() []string {
+	var names []string
+	for _, backend := range aggregator.sources() {
+		if aggregator.session(backend).State().Active() {
+			names = append(names, backend.Name())
+		}
+	}
+
+	return names
+}
+
+// State 合并后的状态 任一服务运行中即为运行中
+func (aggregator *Aggregator) State() SessionState {
+	aggregator.m.Lock()
+	defer aggregator.m.Unlock()
+
+	has := make(map[SessionState]bool)
+	for _, session := range aggregator.sessions {
+		has[session.State()] = true
+	}
+	for _, state := range []SessionState{SessionRunning, SessionReconnecting, SessionStarting, SessionStopping, SessionFailed} {
+		if has[state] {
+			return state
+		}
+	}
+
+	return SessionIdle
+}
+
+// StateText 合并后的状态文字
+func (aggregator *Aggregator) StateText() binding.String {
+	return aggregator.stateText
+}
+
+// StartMonitor 启动names对应服务的监控 结果发布到bus 返回每个启动失败服务的错误
+func (aggregator *Aggregator) StartMonitor(bus *EventBus, names []string) map[string]error {
+	selected := make(map[string]bool, len(names))
+	for _, name := range names {
+		selected[name] = true
+	}
+
+	var sessions []*Session
+	for _, backend := range aggregator.sources() {
+		if selected[backend.Name()] {
+			sessions = append(sessions, aggregator.session(backend))
+		}
+	}
+	if len(sessions) == 0 {
+		return map[string]error{"": errors.New("未选择服务")}
+	}
+
+	return aggregator.each(sessions, func(session *Session) error {
+		return session.Start(bus)
+	})
+}
+
+// StopMonitor 停止全部服务的监控 返回每个停止失败服务的错误
+func (aggregator *Aggregator) StopMonitor() map[string]error {
+	aggregator.m.Lock()
+	var sessions []*Session
+	for _, session := range aggregator.sessions {
+		if session.State().Active() {
+			sessions = append(sessions, session)
+		}
+	}
+	aggregator.m.Unlock()
+
+	return aggregator.each(sessions, (*Session).Stop)
+}
+
+// each 并发执行 一个服务阻塞或失败不影响其他服务
+func (aggregator *Aggregator) each(sessions []*Session, f func(*Session) error) map[string]error {
+	var wg sync.WaitGroup
+	var errM sync.Mutex
+	errs := make(map[string]error)
+	for _, session := range sessions {
+		wg.Add(1)
+		go func(session *Session) {
+			defer wg.Done()
+			if err := f(session); err != nil {
+				log.Error().Caller().Str("source", session.Name()).Msg(err.Error())
+				errM.Lock()
+				errs[session.Name()] = err
+				errM.Unlock()
+			}
+		}(session)
+	}
+	wg.Wait()
+
+	return errs
+}
+
+func (aggregator *Aggregator) session(backend MonitorBackend) *Session {
+	aggregator.m.Lock()
+	defer aggregator.m.Unlock()
+
+	session, ok := aggregator.sessions[backend.Name()]
+	if ok && (session.backend == backend || session.State().Active()) {
+		return session
+	}
+
+	session = NewSession(backend)
+	session.StateText().AddListener(binding.NewDataListener(func() {
+		_ = aggregator.stateText.Set(aggregator.State().String())
+	}))
+	aggregator.sessions[backend.Name()] = session
+
+	return session
+}
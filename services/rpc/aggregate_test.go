@@ -0,0 +1,51 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type failedBackend struct {
+	*MemoryBackend
+}
+
+func (backend *failedBackend) OpenMonitor(_ context.Context) (MonitorStream, error) {
+	return nil, errors.New("connection refused")
+}
+
+func TestAggregator(t *testing.T) {
+	hk := NewMemoryBackend()
+	hk.SourceName = "hk"
+	assert.Nil(t, hk.SetUrl("https://www.baidu.com", 100))
+	us := &failedBackend{NewMemoryBackend()}
+	us.SourceName = "us"
+	aggregator := NewAggregator(func() []MonitorBackend {
+		return []MonitorBackend{hk, us}
+	})
+	assert.Equal(t, []string{"hk", "us"}, aggregator.Sources())
+
+	bus := NewEventBus()
+	queue := bus.Subscribe("test", 100)
+	errs := aggregator.StartMonitor(bus, []string{"hk", "us"})
+	assert.Len(t, errs, 1)
+	assert.NotNil(t, errs["us"])
+	assert.Equal(t, []string{"hk"}, aggregator.Running())
+	assert.Equal(t, SessionRunning, aggregator.State())
+	assert.Equal(t, SessionFailed, aggregator.Session("us").State())
+
+	select {
+	case res := <-queue.C:
+		assert.Equal(t, "hk", res.Source)
+		assert.Equal(t, "https://www.baidu.com", res.Url)
+	case <-time.After(time.Second):
+		t.Fatal("no monitor result")
+	}
+
+	assert.Empty(t, aggregator.StopMonitor())
+	assert.Empty(t, aggregator.Running())
+	assert.Equal(t, SessionFailed, aggregator.State())
+}
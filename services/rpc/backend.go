@@ -0,0 +1,32 @@
+package rpc
+
+import (
+	"context"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+)
+
+// MonitorBackend 监控后端 页面通过它管理url 代理 以及启停监控
+type MonitorBackend interface {
+	// Name 服务名称 用于标记监控结果的来源
+	Name() string
+
+	ListUrl() ([]string, error)
+	ListUrlInterval() (map[string]int32, error)
+	SetUrl(url string, interval int32) error
+	DeleteUrl(url string) error
+
+	ListProxy() ([]string, error)
+	SetProxy(proxy string) error
+	DeleteProxy(proxy string) error
+
+	// OpenMonitor 打开监控结果流 ctx取消时关闭
+	OpenMonitor(ctx context.Context) (MonitorStream, error)
+	// StopMonitor 通知服务停止监控 已打开的流收到io.EOF
+	StopMonitor() error
+}
+
+// MonitorStream 监控结果流 Recv阻塞到下一个结果
+type MonitorStream interface {
+	Recv() (*httpMonitorRpc.MonitorResponse, error)
+}
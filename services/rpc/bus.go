@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/rs/zerolog/log"
+)
+
+var eventBus *EventBus
+var eventBusOnce sync.Once
+
+// EventBus 监控结果的发布订阅 每个订阅者有自己的缓冲
+// Subscribe的缓冲已满时丢弃该订阅者的结果 不阻塞监控流和其他订阅者 用于界面与导出
+// SubscribeLossless不丢弃结果 用于历史记录与告警
+type EventBus struct {
+	m           sync.RWMutex
+	subscribers map[*Subscription]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subscribers: make(map[*Subscription]struct{})}
+}
+
+func GetEventBus() *EventBus {
+	eventBusOnce.Do(func() {
+		eventBus = NewEventBus()
+	})
+	return eventBus
+}
+
+// Subscription 一个订阅者 从C读取结果 取消订阅后C关闭
+type Subscription struct {
+	C <-chan *MonitorResult
+
+	name    string
+	ch      chan *MonitorResult
+	bus     *EventBus
+	dropped int64
+
+	//不丢弃的订阅者 发布时追加到queue 由forward依次送到ch
+	lossless bool
+	qm       sync.Mutex
+	queue    []*MonitorResult
+	closed   bool
+	notify   chan struct{}
+}
+
+// Subscribe name用于日志 size为缓冲大小
+func (bus *EventBus) Subscribe(name string, size int) *Subscription {
+	ch := make(chan *MonitorResult, size)
+	sub := &Subscription{C: ch, name: name, ch: ch, bus: bus}
+
+	bus.m.Lock()
+	bus.subscribers[sub] = struct{}{}
+	bus.m.Unlock()
+
+	return sub
+}
+
+// losslessBuffer SubscribeLossless的通道缓冲 读取方可以按批处理
+const losslessBuffer = 100
+
+// SubscribeLossless 不丢弃结果的订阅 待读取的结果在内存中排队
+// 取消订阅后C在送完排队的结果后关闭 读取方必须读到C关闭为止
+func (bus *EventBus) SubscribeLossless(name string) *Subscription {
+	ch := make(chan *MonitorResult, losslessBuffer)
+	sub := &Subscription{C: ch, name: name, ch: ch, bus: bus, lossless: true, notify: make(chan struct{}, 1)}
+	go sub.forward()
+
+	bus.m.Lock()
+	bus.subscribers[sub] = struct{}{}
+	bus.m.Unlock()
+
+	return sub
+}
+
+func (sub *Subscription) push(res *MonitorResult) {
+	sub.qm.Lock()
+	sub.queue = append(sub.queue, res)
+	sub.qm.Unlock()
+
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// forward 把排队的结果送到ch 取消订阅后送完再关闭ch
+func (sub *Subscription) forward() {
+	for {
+		sub.qm.Lock()
+		queue, closed := sub.queue, sub.closed
+		sub.queue = nil
+		sub.qm.Unlock()
+
+		for _, res := range queue {
+			sub.ch <- res
+		}
+		if closed {
+			close(sub.ch)
+			return
+		}
+		<-sub.notify
+	}
+}
+
+// Publish 发送给全部订阅者
+func (bus *EventBus) Publish(res *MonitorResult) {
+	bus.m.RLock()
+	defer bus.m.RUnlock()
+
+	for sub := range bus.subscribers {
+		if sub.lossless {
+			sub.push(res)
+			continue
+		}
+		select {
+		case sub.ch <- res:
+		default:
+			if dropped := atomic.AddInt64(&sub.dropped, 1); dropped == 1 || dropped%100 == 0 {
+				log.Warn().Caller().Str("subscriber", sub.name).Int64("dropped", dropped).Msg("subscriber is slow, result dropped")
+			}
+		}
+	}
+}
+
+// Subscribers 当前订阅者数量
+func (bus *EventBus) Subscribers() int {
+	bus.m.RLock()
+	defer bus.m.RUnlock()
+	return len(bus.subscribers)
+}
+
+// Unsubscribe 可重复调用
+func (sub *Subscription) Unsubscribe() {
+	sub.bus.m.Lock()
+	defer sub.bus.m.Unlock()
+
+	if _, ok := sub.bus.subscribers[sub]; !ok {
+		return
+	}
+	delete(sub.bus.subscribers, sub)
+	if !sub.lossless {
+		close(sub.ch)
+		return
+	}
+	//Publish持有读锁 此后不会再有结果入队
+	sub.qm.Lock()
+	sub.closed = true
+	sub.qm.Unlock()
+	select {
+	case sub.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Dropped 因缓冲已满丢弃的结果数 SubscribeLossless始终为0
+func (sub *Subscription) Dropped() int64 {
+	return atomic.LoadInt64(&sub.dropped)
+}
@@ -0,0 +1,56 @@
+package rpc
+
+import (
+	"testing"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventBus(t *testing.T) {
+	bus := NewEventBus()
+	fast := bus.Subscribe("fast", 10)
+	slow := bus.Subscribe("slow", 1)
+	assert.Equal(t, 2, bus.Subscribers())
+
+	for i := 0; i < 3; i++ {
+		bus.Publish(&MonitorResult{Source: "local", MonitorResponse: &httpMonitorRpc.MonitorResponse{Url: "https://www.baidu.com"}})
+	}
+	assert.Len(t, fast.C, 3)
+	assert.Len(t, slow.C, 1)
+	assert.Equal(t, int64(0), fast.Dropped())
+	assert.Equal(t, int64(2), slow.Dropped())
+
+	//取消订阅后通道关闭 不再收到结果
+	slow.Unsubscribe()
+	slow.Unsubscribe()
+	assert.Equal(t, 1, bus.Subscribers())
+	<-slow.C
+	_, ok := <-slow.C
+	assert.False(t, ok)
+
+	bus.Publish(&MonitorResult{Source: "local", MonitorResponse: &httpMonitorRpc.MonitorResponse{Url: "https://www.baidu.com"}})
+	assert.Len(t, fast.C, 4)
+	fast.Unsubscribe()
+	assert.Equal(t, 0, bus.Subscribers())
+}
+
+func TestEventBusLossless(t *testing.T) {
+	bus := NewEventBus()
+	sub := bus.SubscribeLossless("history")
+	//读取方未读取时也不丢弃
+	for i := 0; i < 1000; i++ {
+		bus.Publish(&MonitorResult{Source: "local", MonitorResponse: &httpMonitorRpc.MonitorResponse{Url: "https://www.baidu.com"}})
+	}
+	sub.Unsubscribe()
+	sub.Unsubscribe()
+	assert.Equal(t, 0, bus.Subscribers())
+
+	//取消订阅后送完排队的结果再关闭
+	count := 0
+	for range sub.C {
+		count++
+	}
+	assert.Equal(t, 1000, count)
+	assert.Equal(t, int64(0), sub.Dropped())
+}
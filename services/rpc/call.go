@@ -0,0 +1,74 @@
+package rpc
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// CallOptions grpc调用的超时与重试配置
+type CallOptions struct {
+	// Timeout 默认超时
+	Timeout time.Duration
+	// MethodTimeouts 按方法名覆盖超时 eg:GetAllDomainAndInterval
+	MethodTimeouts map[string]time.Duration
+	// Retry 幂等调用的重试间隔与重试次数
+	Retry Backoff
+}
+
+var DefaultCallOptions = CallOptions{
+	Timeout: 5 * time.Second,
+	Retry:   Backoff{Initial: 200 * time.Millisecond, Max: 2 * time.Second, MaxAttempts: 3},
+}
+
+func (opts CallOptions) timeout(method string) time.Duration {
+	if timeout, ok := opts.MethodTimeouts[method]; ok {
+		return timeout
+	}
+	return opts.Timeout
+}
+
+// retryable 服务不可用时可以重试
+// 超时不重试 调用多在界面线程中 服务无响应时重试会让界面卡住数倍超时时间
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// invoke 带超时调用method idempotent为true时失败按Retry重试
+func (backend *GrpcBackend) invoke(method string, idempotent bool, call func(ctx context.Context, conn *grpc.ClientConn) error) error {
+	opts := backend.CallOptions()
+	for attempt := 1; ; attempt++ {
+		err := backend.invokeOnce(method, opts, call)
+		if err == nil || !idempotent || !retryable(err) || attempt > opts.Retry.MaxAttempts {
+			return err
+		}
+
+		delay := opts.Retry.Delay(attempt)
+		log.Debug().Str("method", method).Int("attempt", attempt).Dur("delay", delay).Msg("retry: " + err.Error())
+		time.Sleep(delay)
+	}
+}
+
+func (backend *GrpcBackend) invokeOnce(method string, opts CallOptions, call func(ctx context.Context, conn *grpc.ClientConn) error) error {
+	conn, err := backend.conn()
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if timeout := opts.timeout(method); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	return call(ctx, conn)
+}
@@ -0,0 +1,102 @@
+package rpc
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// flakyUrlService 前failures次调用返回Unavailable GetAll始终超过超时时间
+type flakyUrlService struct {
+	httpMonitorRpc.UnimplementedUrlServiceServer
+	m        sync.Mutex
+	calls    map[string]int
+	failures int
+}
+
+func (service *flakyUrlService) fail(method string) error {
+	service.m.Lock()
+	defer service.m.Unlock()
+	service.calls[method]++
+	if service.calls[method] <= service.failures {
+		return status.Error(codes.Unavailable, "daemon restarting")
+	}
+	return nil
+}
+
+func (service *flakyUrlService) count(method string) int {
+	service.m.Lock()
+	defer service.m.Unlock()
+	return service.calls[method]
+}
+
+func (service *flakyUrlService) GetAllProxy(context.Context, *empty.Empty) (*httpMonitorRpc.ProxyListResponse, error) {
+	if err := service.fail("GetAllProxy"); err != nil {
+		return nil, err
+	}
+	return &httpMonitorRpc.ProxyListResponse{ProxyList: []string{"socks5://127.0.0.1:8000"}}, nil
+}
+
+func (service *flakyUrlService) SetProxy(context.Context, *httpMonitorRpc.ProxyRequest) (*httpMonitorRpc.ProxyResponse, error) {
+	if err := service.fail("SetProxy"); err != nil {
+		return nil, err
+	}
+	return &httpMonitorRpc.ProxyResponse{Result: "ok"}, nil
+}
+
+func (service *flakyUrlService) GetAll(ctx context.Context, _ *empty.Empty) (*httpMonitorRpc.UrlListResponse, error) {
+	_ = service.fail("GetAll")
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestGrpcBackendRetry(t *testing.T) {
+	service := &flakyUrlService{calls: make(map[string]int), failures: 2}
+	address := startTestServer(t, func(s *grpc.Server) {
+		httpMonitorRpc.RegisterUrlServiceServer(s, service)
+	})
+
+	conns := NewConnManager()
+	defer conns.Close()
+	backend := NewGrpcBackend(conns, Profile{Name: "local", Address: address, ServerName: "test.com"})
+	backend.SetCallOptions(CallOptions{
+		Timeout:        time.Second,
+		MethodTimeouts: map[string]time.Duration{"GetAll": 50 * time.Millisecond},
+		Retry:          Backoff{Initial: time.Millisecond, Max: 5 * time.Millisecond, MaxAttempts: 2},
+	})
+
+	//幂等调用失败后重试
+	proxies, err := backend.ListProxy()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"socks5://127.0.0.1:8000"}, proxies)
+	assert.Equal(t, 3, service.count("GetAllProxy"))
+
+	//非幂等调用不重试
+	err = backend.SetProxy("socks5://127.0.0.1:8000")
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, 1, service.count("SetProxy"))
+
+	//超时不重试
+	start := time.Now()
+	_, err = backend.ListUrl()
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, 1, service.count("GetAll"))
+	assert.Less(t, time.Since(start), time.Second)
+
+	stats := make(map[string]CallStat)
+	for _, stat := range CallStats() {
+		stats[stat.Method] = stat
+	}
+	stat := stats["/rpc.UrlService/GetAllProxy"]
+	assert.Equal(t, int64(3), stat.Count)
+	assert.Equal(t, int64(2), stat.Errors)
+	assert.Equal(t, int64(1), stats["/rpc.UrlService/GetAll"].Errors)
+}
@@ -0,0 +1,246 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/connectivity"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+// HealthInterval 健康检查间隔
+var HealthInterval = 10 * time.Second
+
+type HealthStatus int
+
+const (
+	HealthUnknown HealthStatus = iota
+	HealthServing
+	HealthNotServing
+	// HealthUnimplemented 服务未注册grpc健康检查 以连接状态为准
+	HealthUnimplemented
+)
+
+func (health HealthStatus) String() string {
+	switch health {
+	case HealthServing:
+		return "健康"
+	case HealthNotServing:
+		return "不健康"
+	case HealthUnimplemented:
+		return "未提供健康检查"
+	}
+	return "未知"
+}
+
+// ManagedConn 一个连接配置对应的长连接 持续监听连接状态并定时健康检查
+type ManagedConn struct {
+	profile   Profile
+	conn      *grpc.ClientConn
+	stateText binding.String
+	cancel    context.CancelFunc
+	done      chan struct{}
+
+	m      sync.Mutex
+	health HealthStatus
+}
+
+func newManagedConn(profile Profile) (*ManagedConn, error) {
+	conn, err := GetRpcConn(profile)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	managed := &ManagedConn{
+		profile:   profile,
+		conn:      conn,
+		stateText: binding.NewString(),
+		cancel:    cancel,
+		done:      make(chan struct{}),
+	}
+	managed.refresh()
+	conn.Connect()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		managed.watch(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		managed.checkHealth(ctx)
+	}()
+	go func() {
+		wg.Wait()
+		close(managed.done)
+	}()
+
+	return managed, nil
+}
+
+func (managed *ManagedConn) Conn() *grpc.ClientConn {
+	return managed.conn
+}
+
+func (managed *ManagedConn) Profile() Profile {
+	return managed.profile
+}
+
+// State grpc连接状态
+func (managed *ManagedConn) State() connectivity.State {
+	return managed.conn.GetState()
+}
+
+func (managed *ManagedConn) Health() HealthStatus {
+	managed.m.Lock()
+	defer managed.m.Unlock()
+	return managed.health
+}
+
+// StateText 连接状态与健康状态文字 可直接绑定到label
+func (managed *ManagedConn) StateText() binding.String {
+	return managed.stateText
+}
+
+func (managed *ManagedConn) Close() error {
+	managed.cancel()
+	err := managed.conn.Close()
+	<-managed.done
+
+	return err
+}
+
+func (managed *ManagedConn) refresh() {
+	_ = managed.stateText.Set(managed.State().String() + "/" + managed.Health().String())
+}
+
+func (managed *ManagedConn) watch(ctx context.Context) {
+	for {
+		state := managed.conn.GetState()
+		log.Debug().Str("profile", managed.profile.Name).Str("state", state.String()).Send()
+		managed.refresh()
+		if state == connectivity.Shutdown || !managed.conn.WaitForStateChange(ctx, state) {
+			return
+		}
+	}
+}
+
+func (managed *ManagedConn) checkHealth(ctx context.Context) {
+	client := healthpb.NewHealthClient(managed.conn)
+	ticker := time.NewTicker(HealthInterval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, HealthInterval)
+		res, err := client.Check(checkCtx, &healthpb.HealthCheckRequest{})
+		cancel()
+
+		health := HealthUnknown
+		switch {
+		case err == nil && res.Status == healthpb.HealthCheckResponse_SERVING:
+			health = HealthServing
+		case err == nil:
+			health = HealthNotServing
+		case status.Code(err) == codes.Unimplemented:
+			health = HealthUnimplemented
+		case ctx.Err() == nil:
+			log.Debug().Str("profile", managed.profile.Name).Msg("health check failed: " + err.Error())
+		}
+		managed.m.Lock()
+		managed.health = health
+		managed.m.Unlock()
+		managed.refresh()
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// ConnManager 按连接配置名称管理长连接 配置变化时重建连接
+type ConnManager struct {
+	m      sync.Mutex
+	conns  map[string]*ManagedConn
+	closed bool
+}
+
+func NewConnManager() *ConnManager {
+	return &ConnManager{conns: make(map[string]*ManagedConn)}
+}
+
+// Get 返回profile对应的连接 不存在或配置已变化时重新建立
+func (manager *ConnManager) Get(profile Profile) (*ManagedConn, error) {
+	manager.m.Lock()
+	defer manager.m.Unlock()
+
+	if manager.closed {
+		return nil, errors.New("连接已关闭")
+	}
+
+	if managed, ok := manager.conns[profile.Name]; ok {
+		if managed.profile == profile {
+			return managed, nil
+		}
+		if err := managed.Close(); err != nil {
+			log.Error().Caller().Str("profile", profile.Name).Msg(err.Error())
+		}
+		delete(manager.conns, profile.Name)
+	}
+
+	managed, err := newManagedConn(profile)
+	if err != nil {
+		return nil, err
+	}
+	manager.conns[profile.Name] = managed
+
+	return managed, nil
+}
+
+// Remove 关闭并移除name对应的连接 配置删除时调用
+func (manager *ConnManager) Remove(name string) error {
+	manager.m.Lock()
+	managed, ok := manager.conns[name]
+	delete(manager.conns, name)
+	manager.m.Unlock()
+
+	if !ok {
+		return nil
+	}
+	return managed.Close()
+}
+
+// Lookup 返回已建立的连接 不会新建
+func (manager *ConnManager) Lookup(name string) *ManagedConn {
+	manager.m.Lock()
+	defer manager.m.Unlock()
+	return manager.conns[name]
+}
+
+// Close 关闭全部连接 应用退出时调用
+func (manager *ConnManager) Close() error {
+	manager.m.Lock()
+	defer manager.m.Unlock()
+
+	var lastErr error
+	for name, managed := range manager.conns {
+		if err := managed.Close(); err != nil {
+			log.Error().Caller().Str("profile", name).Msg(err.Error())
+			lastErr = err
+		}
+	}
+	manager.conns = make(map[string]*ManagedConn)
+	manager.closed = true
+
+	return lastErr
+}
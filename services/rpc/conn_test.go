@@ -0,0 +1,93 @@
+package rpc
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/flyflyhe/httpMonitor/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/connectivity"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// startTestServer 启动与httpMonitor服务相同证书的grpc服务 返回监听地址
+func startTestServer(t *testing.T, register func(s *grpc.Server)) string {
+	certPool := x509.NewCertPool()
+	certPool.AppendCertsFromPEM(config.GetRoot())
+	serverCert, err := tls.X509KeyPair(config.GetServerCertChain(), config.GetServerPrivateKey())
+	assert.Nil(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{serverCert},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    certPool,
+	})))
+	register(s)
+	go func() {
+		_ = s.Serve(lis)
+	}()
+	t.Cleanup(s.Stop)
+
+	return lis.Addr().String()
+}
+
+func waitConn(t *testing.T, managed *ManagedConn, state connectivity.State, health HealthStatus) {
+	deadline := time.Now().Add(5 * time.Second)
+	for managed.State() != state || managed.Health() != health {
+		if time.Now().After(deadline) {
+			t.Fatalf("conn %s/%s, want %s/%s", managed.State(), managed.Health(), state, health)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestConnManager(t *testing.T) {
+	HealthInterval = 50 * time.Millisecond
+	healthServer := health.NewServer()
+	address := startTestServer(t, func(s *grpc.Server) {
+		healthpb.RegisterHealthServer(s, healthServer)
+	})
+	profile := Profile{Name: "local", Address: address, ServerName: "test.com"}
+
+	manager := NewConnManager()
+	managed, err := manager.Get(profile)
+	assert.Nil(t, err)
+	waitConn(t, managed, connectivity.Ready, HealthServing)
+	text, _ := managed.StateText().Get()
+	assert.Equal(t, "READY/健康", text)
+
+	again, _ := manager.Get(profile)
+	assert.Same(t, managed, again)
+	assert.Same(t, managed, manager.Lookup("local"))
+
+	healthServer.SetServingStatus("", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitConn(t, managed, connectivity.Ready, HealthNotServing)
+
+	//配置变化后重建连接 旧连接关闭
+	profile.ServerName = "other.com"
+	changed, err := manager.Get(profile)
+	assert.Nil(t, err)
+	assert.NotSame(t, managed, changed)
+	assert.Equal(t, connectivity.Shutdown, managed.State())
+
+	//删除配置时关闭连接
+	assert.Nil(t, manager.Remove("local"))
+	assert.Equal(t, connectivity.Shutdown, changed.State())
+	assert.Nil(t, manager.Lookup("local"))
+	assert.Nil(t, manager.Remove("local"))
+
+	changed, err = manager.Get(profile)
+	assert.Nil(t, err)
+	assert.Nil(t, manager.Close())
+	assert.Equal(t, connectivity.Shutdown, changed.State())
+	_, err = manager.Get(profile)
+	assert.NotNil(t, err)
+}
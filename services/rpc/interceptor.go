@@ -0,0 +1,145 @@
+package rpc
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// CallStat 单个grpc方法的调用统计
+type CallStat struct {
+	Method string
+	Count  int64
+	Errors int64
+	Total  time.Duration
+	Max    time.Duration
+	Last   time.Duration
+}
+
+// Avg 平均耗时
+func (stat CallStat) Avg() time.Duration {
+	if stat.Count == 0 {
+		return 0
+	}
+	return stat.Total / time.Duration(stat.Count)
+}
+
+var (
+	callStatsLock sync.Mutex
+	callStats     = make(map[string]*CallStat)
+)
+
+func recordCall(method string, latency time.Duration, err error) {
+	callStatsLock.Lock()
+	defer callStatsLock.Unlock()
+
+	stat, ok := callStats[method]
+	if !ok {
+		stat = &CallStat{Method: method}
+		callStats[method] = stat
+	}
+	stat.Count++
+	if err != nil {
+		stat.Errors++
+	}
+	stat.Total += latency
+	stat.Last = latency
+	if latency > stat.Max {
+		stat.Max = latency
+	}
+}
+
+// CallStats 全部grpc方法的调用统计 按方法名排序
+func CallStats() []CallStat {
+	callStatsLock.Lock()
+	defer callStatsLock.Unlock()
+
+	stats := make([]CallStat, 0, len(callStats))
+	for _, stat := range callStats {
+		stats = append(stats, *stat)
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Method < stats[j].Method
+	})
+
+	return stats
+}
+
+// unaryLogInterceptor 记录每次调用的耗时与结果
+func unaryLogInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	start := time.Now()
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	latency := time.Since(start)
+	recordCall(method, latency, err)
+
+	event := log.Debug()
+	if err != nil {
+		event = log.Error()
+	}
+	event.Str("target", cc.Target()).
+		Str("method", method).
+		Str("code", status.Code(err).String()).
+		Dur("latency", latency).
+		Err(err).
+		Msg("grpc call")
+
+	return err
+}
+
+// streamLogInterceptor 记录打开流的耗时 流结束时记录持续时间与收到的消息数
+func streamLogInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	start := time.Now()
+	stream, err := streamer(ctx, desc, cc, method, opts...)
+	latency := time.Since(start)
+	recordCall(method, latency, err)
+
+	event := log.Debug()
+	if err != nil {
+		event = log.Error()
+	}
+	event.Str("target", cc.Target()).
+		Str("method", method).
+		Str("code", status.Code(err).String()).
+		Dur("latency", latency).
+		Err(err).
+		Msg("grpc stream open")
+	if err != nil {
+		return nil, err
+	}
+
+	return &loggedStream{ClientStream: stream, target: cc.Target(), method: method, start: start}, nil
+}
+
+type loggedStream struct {
+	grpc.ClientStream
+	target   string
+	method   string
+	start    time.Time
+	received int64
+	once     sync.Once
+}
+
+func (stream *loggedStream) RecvMsg(m interface{}) error {
+	err := stream.ClientStream.RecvMsg(m)
+	if err == nil {
+		stream.received++
+		return nil
+	}
+
+	stream.once.Do(func() {
+		log.Debug().Str("target", stream.target).
+			Str("method", stream.method).
+			Str("code", status.Code(err).String()).
+			Dur("duration", time.Since(stream.start)).
+			Int64("received", stream.received).
+			Err(err).
+			Msg("grpc stream closed")
+	})
+
+	return err
+}
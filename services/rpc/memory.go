@@ -0,0 +1,239 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+)
+
+// MemoryBackend 内存实现 不依赖httpMonitor服务 用于测试与演示
+type MemoryBackend struct {
+	// SourceName 服务名称 默认memory
+	SourceName string
+
+	m sync.RWMutex
+	// checkFunc 返回url经proxy访问的结果 为空时全部返回success
+	checkFunc func(url, proxy string) string
+	urls      map[string]int32
+	proxies   []string
+	stopChan  chan struct{}
+}
+
+var _ MonitorBackend = (*MemoryBackend)(nil)
+
+func NewMemoryBackend() *MemoryBackend {
+	return &MemoryBackend{urls: make(map[string]int32)}
+}
+
+func (backend *MemoryBackend) Name() string {
+	if backend.SourceName == "" {
+		return "memory"
+	}
+	return backend.SourceName
+}
+
+// SetCheck 设置检测结果 监控运行时也可以修改
+func (backend *MemoryBackend) SetCheck(f func(url, proxy string) string) {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	backend.checkFunc = f
+}
+
+func (backend *MemoryBackend) ListUrl() ([]string, error) {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+
+	urls := make([]string, 0, len(backend.urls))
+	for url := range backend.urls {
+		urls = append(urls, url)
+	}
+	sort.Strings(urls)
+
+	return urls, nil
+}
+
+func (backend *MemoryBackend) ListUrlInterval() (map[string]int32, error) {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+
+	urlInterval := make(map[string]int32, len(backend.urls))
+	for url, interval := range backend.urls {
+		urlInterval[url] = interval
+	}
+
+	return urlInterval, nil
+}
+
+func (backend *MemoryBackend) SetUrl(url string, interval int32) error {
+	if url == "" {
+		return errors.New("url不能为空")
+	}
+	if interval <= 0 {
+		return errors.New("间隔时间必须大于0")
+	}
+
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	backend.urls[url] = interval
+
+	return nil
+}
+
+func (backend *MemoryBackend) DeleteUrl(url string) error {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	delete(backend.urls, url)
+
+	return nil
+}
+
+func (backend *MemoryBackend) ListProxy() ([]string, error) {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+
+	return append([]string(nil), backend.proxies...), nil
+}
+
+func (backend *MemoryBackend) SetProxy(proxy string) error {
+	if proxy == "" {
+		return errors.New("代理不能为空")
+	}
+
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	for _, v := range backend.proxies {
+		if v == proxy {
+			return nil
+		}
+	}
+	backend.proxies = append(backend.proxies, proxy)
+
+	return nil
+}
+
+func (backend *MemoryBackend) DeleteProxy(proxy string) error {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	for i, v := range backend.proxies {
+		if v == proxy {
+			backend.proxies = append(backend.proxies[:i], backend.proxies[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// memoryStream 内存监控流 StopMonitor后返回io.EOF
+type memoryStream struct {
+	ctx      context.Context
+	results  chan *httpMonitorRpc.MonitorResponse
+	stopChan chan struct{}
+}
+
+func (stream *memoryStream) Recv() (*httpMonitorRpc.MonitorResponse, error) {
+	select {
+	case res := <-stream.results:
+		return res, nil
+	case <-stream.stopChan:
+		return nil, io.EOF
+	case <-stream.ctx.Done():
+		return nil, stream.ctx.Err()
+	}
+}
+
+func (backend *MemoryBackend) OpenMonitor(ctx context.Context) (MonitorStream, error) {
+	backend.m.Lock()
+	if backend.stopChan != nil {
+		backend.m.Unlock()
+		return nil, errors.New("监控已启动")
+	}
+	stopChan := make(chan struct{})
+	backend.stopChan = stopChan
+	backend.m.Unlock()
+
+	stream := &memoryStream{ctx: ctx, results: make(chan *httpMonitorRpc.MonitorResponse), stopChan: stopChan}
+	go func() {
+		defer func() {
+			backend.m.Lock()
+			if backend.stopChan == stopChan {
+				backend.stopChan = nil
+			}
+			backend.m.Unlock()
+		}()
+
+		ticker := time.NewTicker(100 * time.Millisecond)
+		defer ticker.Stop()
+		lastCheck := make(map[string]time.Time)
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ctx.Done():
+				return
+			case now := <-ticker.C:
+				for _, url := range backend.dueUrls(lastCheck, now) {
+					lastCheck[url] = now
+					select {
+					case stream.results <- backend.check(url):
+					case <-stopChan:
+						return
+					case <-ctx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return stream, nil
+}
+
+func (backend *MemoryBackend) StopMonitor() error {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	if backend.stopChan != nil {
+		close(backend.stopChan)
+		backend.stopChan = nil
+	}
+
+	return nil
+}
+
+// dueUrls 返回到达检测时间的url
+func (backend *MemoryBackend) dueUrls(lastCheck map[string]time.Time, now time.Time) []string {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+
+	var due []string
+	for url, interval := range backend.urls {
+		if last, ok := lastCheck[url]; !ok || now.Sub(last) >= time.Duration(interval)*time.Millisecond {
+			due = append(due, url)
+		}
+	}
+
+	return due
+}
+
+func (backend *MemoryBackend) check(url string) *httpMonitorRpc.MonitorResponse {
+	backend.m.RLock()
+	proxies := append([]string{""}, backend.proxies...)
+	checkFunc := backend.checkFunc
+	backend.m.RUnlock()
+
+	result := make(map[string]string, len(proxies))
+	for _, proxy := range proxies {
+		if checkFunc != nil {
+			result[proxy] = checkFunc(url, proxy)
+		} else {
+			result[proxy] = "success"
+		}
+	}
+
+	return &httpMonitorRpc.MonitorResponse{Url: url, Result: result}
+}
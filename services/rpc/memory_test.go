@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryBackend(t *testing.T) {
+	backend := NewMemoryBackend()
+	backend.SetCheck(func(url, proxy string) string {
+		if proxy == "" {
+			return "success"
+		}
+		return "timeout"
+	})
+
+	assert.Nil(t, backend.SetUrl("https://www.baidu.com", 100))
+	assert.NotNil(t, backend.SetUrl("https://www.baidu.com", 0))
+	assert.Nil(t, backend.SetProxy("socks5://127.0.0.1:8000"))
+	assert.Nil(t, backend.SetProxy("socks5://127.0.0.1:8000"))
+
+	urls, _ := backend.ListUrlInterval()
+	assert.Equal(t, map[string]int32{"https://www.baidu.com": 100}, urls)
+	proxies, _ := backend.ListProxy()
+	assert.Equal(t, []string{"socks5://127.0.0.1:8000"}, proxies)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, err := backend.OpenMonitor(ctx)
+	assert.Nil(t, err)
+	_, err = backend.OpenMonitor(ctx)
+	assert.NotNil(t, err)
+
+	res, err := stream.Recv()
+	assert.Nil(t, err)
+	assert.Equal(t, "https://www.baidu.com", res.Url)
+	assert.Equal(t, map[string]string{"": "success", "socks5://127.0.0.1:8000": "timeout"}, res.Result)
+
+	assert.Nil(t, backend.StopMonitor())
+	_, err = stream.Recv()
+	assert.Equal(t, io.EOF, err)
+
+	assert.Nil(t, backend.DeleteUrl("https://www.baidu.com"))
+	assert.Nil(t, backend.DeleteProxy("socks5://127.0.0.1:8000"))
+	urlList, _ := backend.ListUrl()
+	assert.Empty(t, urlList)
+	proxies, _ = backend.ListProxy()
+	assert.Empty(t, proxies)
+}
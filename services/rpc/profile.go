@@ -0,0 +1,216 @@
+package rpc
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"os"
+	"sync"
+
+	"fyne.io/fyne/v2"
+)
+
+const (
+	preferenceProfiles      = "rpcProfiles"
+	preferenceActiveProfile = "rpcActiveProfile"
+)
+
+// Profile httpMonitor服务的连接配置 证书路径为空时使用内置证书
+type Profile struct {
+	Name       string
+	Address    string
+	ServerName string
+	CaFile     string
+	CertFile   string
+	KeyFile    string
+}
+
+// DefaultProfile 连接本机启动的服务
+var DefaultProfile = Profile{Name: "local", Address: address, ServerName: "test.com"}
+
+func (p Profile) Validate() error {
+	if p.Name == "" {
+		return errors.New("名称不能为空")
+	}
+	if _, _, err := net.SplitHostPort(p.Address); err != nil {
+		return errors.New("地址格式错误 eg:localhost:50051")
+	}
+	if (p.CertFile == "") != (p.KeyFile == "") {
+		return errors.New("客户端证书与私钥必须同时配置")
+	}
+	for _, file := range []string{p.CaFile, p.CertFile, p.KeyFile} {
+		if file == "" {
+			continue
+		}
+		if _, err := os.Stat(file); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ProfileStore 连接配置 保存在fyne Preferences中
+type ProfileStore struct {
+	prefs           fyne.Preferences
+	m               sync.Mutex
+	onActiveChanged func(Profile)
+	onDeleted       func(name string)
+}
+
+func NewProfileStore(prefs fyne.Preferences) *ProfileStore {
+	return &ProfileStore{prefs: prefs}
+}
+
+// SetOnActiveChanged 切换当前配置时回调
+func (store *ProfileStore) SetOnActiveChanged(f func(Profile)) {
+	store.m.Lock()
+	defer store.m.Unlock()
+	store.onActiveChanged = f
+}
+
+// SetOnDeleted 删除配置后回调 用于关闭对应的连接
+func (store *ProfileStore) SetOnDeleted(f func(name string)) {
+	store.m.Lock()
+	defer store.m.Unlock()
+	store.onDeleted = f
+}
+
+// List 返回全部配置 未保存过配置时返回DefaultProfile
+func (store *ProfileStore) List() []Profile {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.list()
+}
+
+func (store *ProfileStore) Get(name string) (Profile, bool) {
+	for _, p := range store.List() {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return Profile{}, false
+}
+
+// Save 新增或按名称覆盖配置
+func (store *ProfileStore) Save(profile Profile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	store.m.Lock()
+	profiles := store.list()
+	replaced := false
+	for i, p := range profiles {
+		if p.Name == profile.Name {
+			profiles[i] = profile
+			replaced = true
+		}
+	}
+	if !replaced {
+		profiles = append(profiles, profile)
+	}
+	err := store.save(profiles)
+	onActiveChanged := store.onActiveChanged
+	active := store.active(profiles)
+	store.m.Unlock()
+
+	if err == nil && replaced && active.Name == profile.Name && onActiveChanged != nil {
+		onActiveChanged(profile)
+	}
+
+	return err
+}
+
+func (store *ProfileStore) Delete(name string) error {
+	store.m.Lock()
+	//未选择过配置时第一个配置为当前配置 同样不能删除
+	profiles := store.list()
+	if store.active(profiles).Name == name {
+		store.m.Unlock()
+		return errors.New("不能删除当前使用的配置")
+	}
+
+	deleted := false
+	var err error
+	for i, p := range profiles {
+		if p.Name == name {
+			err = store.save(append(profiles[:i], profiles[i+1:]...))
+			deleted = true
+			break
+		}
+	}
+	onDeleted := store.onDeleted
+	store.m.Unlock()
+
+	if err == nil && deleted && onDeleted != nil {
+		onDeleted(name)
+	}
+
+	return err
+}
+
+// Active 当前使用的配置
+func (store *ProfileStore) Active() Profile {
+	store.m.Lock()
+	defer store.m.Unlock()
+
+	return store.active(store.list())
+}
+
+// active 选择的配置不存在时使用第一个配置
+func (store *ProfileStore) active(profiles []Profile) Profile {
+	name := store.prefs.String(preferenceActiveProfile)
+	for _, p := range profiles {
+		if p.Name == name {
+			return p
+		}
+	}
+
+	return profiles[0]
+}
+
+// SetActive 切换当前配置
+func (store *ProfileStore) SetActive(name string) error {
+	profile, ok := store.Get(name)
+	if !ok {
+		return errors.New("配置不存在:" + name)
+	}
+
+	store.m.Lock()
+	store.prefs.SetString(preferenceActiveProfile, name)
+	onActiveChanged := store.onActiveChanged
+	store.m.Unlock()
+
+	if onActiveChanged != nil {
+		onActiveChanged(profile)
+	}
+
+	return nil
+}
+
+func (store *ProfileStore) list() []Profile {
+	var profiles []Profile
+	if str := store.prefs.String(preferenceProfiles); str != "" {
+		if err := json.Unmarshal([]byte(str), &profiles); err != nil {
+			fyne.LogError("load rpc profiles failed", err)
+		}
+	}
+	if len(profiles) == 0 {
+		profiles = []Profile{DefaultProfile}
+	}
+
+	return profiles
+}
+
+func (store *ProfileStore) save(profiles []Profile) error {
+	data, err := json.Marshal(profiles)
+	if err != nil {
+		return err
+	}
+	store.prefs.SetString(preferenceProfiles, string(data))
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package rpc
+
+import (
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfileStore(t *testing.T) {
+	store := NewProfileStore(test.NewApp().Preferences())
+	assert.Equal(t, []Profile{DefaultProfile}, store.List())
+	assert.Equal(t, DefaultProfile, store.Active())
+
+	remote := Profile{Name: "hk", Address: "10.0.0.1:50051", ServerName: "test.com"}
+	assert.Nil(t, store.Save(remote))
+	assert.NotNil(t, store.Save(Profile{Name: "bad", Address: "10.0.0.1"}))
+	assert.NotNil(t, store.Save(Profile{Name: "bad", Address: "10.0.0.1:50051", CertFile: "client.pem"}))
+	//未选择过配置时第一个配置为当前配置 不能删除
+	assert.NotNil(t, store.Delete("local"))
+	assert.Equal(t, DefaultProfile, store.Active())
+
+	var changed Profile
+	store.SetOnActiveChanged(func(p Profile) {
+		changed = p
+	})
+	assert.NotNil(t, store.SetActive("none"))
+	assert.Nil(t, store.SetActive("hk"))
+	assert.Equal(t, remote, changed)
+	assert.Equal(t, remote, store.Active())
+
+	remote.Address = "10.0.0.2:50051"
+	assert.Nil(t, store.Save(remote))
+	assert.Equal(t, remote, changed)
+
+	var deleted []string
+	store.SetOnDeleted(func(name string) {
+		deleted = append(deleted, name)
+	})
+	assert.NotNil(t, store.Delete("hk"))
+	assert.Nil(t, store.SetActive("local"))
+	assert.Nil(t, store.Delete("hk"))
+	assert.Nil(t, store.Delete("hk"))
+	assert.Equal(t, []string{"hk"}, deleted)
+	assert.Equal(t, []Profile{DefaultProfile}, store.List())
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"crypto/tls"
 	"crypto/x509"
-	"errors"
 	"fmt"
 	"github.com/flyflyhe/httpMonitor/config"
 	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
@@ -13,35 +12,16 @@ import (
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials"
-	"io"
+	"os"
 	"sync"
+	"time"
 )
 
-var monitorQueue *MonitorQueue
-var once sync.Once
-
-type MonitorQueue struct {
-	Queue   chan *httpMonitorRpc.MonitorResponse
-	Running bool
-	m       sync.Mutex
-}
-
-func GetMonitorQueue() *MonitorQueue {
-	once.Do(func() {
-		monitorQueue = &MonitorQueue{Queue: make(chan *httpMonitorRpc.MonitorResponse, 100)}
-	})
-	return monitorQueue
-}
-
-var grpcConnPool = sync.Pool{
-	New: func() any {
-		conn, err := GetRpcConn()
-		if err != nil {
-			log.Error().Caller().Str("pool create grpc conn failed", err.Error())
-			return nil
-		}
-		return conn
-	},
+// MonitorResult 带来源服务名称与接收时间的监控结果
+type MonitorResult struct {
+	Source string
+	Time   time.Time
+	*httpMonitorRpc.MonitorResponse
 }
 
 const address = "localhost:50051"
@@ -50,117 +30,120 @@ func Start() {
 	services.Start(address)
 }
 
-func ListUrl() ([]string, error) {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-
-	if !ok {
-		return nil, errors.New("from pool get conn failed")
-	}
-
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
-
-	if res, err := rpcClient.GetAll(context.Background(), &empty.Empty{}); err != nil {
-		return nil, err
-	} else {
-		return res.Urls, nil
-	}
+// GrpcBackend 通过grpc调用httpMonitor服务 连接由ConnManager统一管理
+type GrpcBackend struct {
+	conns       *ConnManager
+	m           sync.RWMutex
+	profile     Profile
+	callOptions CallOptions
 }
 
-func ListUrlInterval() (map[string]int32, error) {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
+var _ MonitorBackend = (*GrpcBackend)(nil)
 
-	if !ok {
-		return nil, errors.New("from pool get conn failed")
-	}
-
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
-
-	if res, err := rpcClient.GetAllDomainAndInterval(context.Background(), &empty.Empty{}); err != nil {
-		return nil, err
-	} else {
-		return res.UrlInterval, nil
-	}
+func NewGrpcBackend(conns *ConnManager, profile Profile) *GrpcBackend {
+	return &GrpcBackend{conns: conns, profile: profile, callOptions: DefaultCallOptions}
 }
 
-func SetUrl(url string, interval int32) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
-	}
-
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
-
-	_, err := rpcClient.SetUrl(context.Background(), &httpMonitorRpc.UrlRequest{Url: url, Interval: interval})
-	return err
+func (backend *GrpcBackend) CallOptions() CallOptions {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+	return backend.callOptions
 }
 
-func DeleteUrl(url string) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
-	}
-
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
+func (backend *GrpcBackend) SetCallOptions(opts CallOptions) {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	backend.callOptions = opts
+}
 
-	_, err := rpcClient.DeleteUrl(context.Background(), &httpMonitorRpc.UrlRequest{Url: url})
-	return err
+func (backend *GrpcBackend) Name() string {
+	return backend.Profile().Name
 }
 
-func ListProxy() ([]string, error) {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return nil, errors.New("from pool get conn failed")
-	}
+func (backend *GrpcBackend) Profile() Profile {
+	backend.m.RLock()
+	defer backend.m.RUnlock()
+	return backend.profile
+}
 
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
+// SetProfile 切换连接的服务 之后的调用都使用新配置
+func (backend *GrpcBackend) SetProfile(profile Profile) {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	backend.profile = profile
+}
 
-	if res, err := rpcClient.GetAllProxy(context.Background(), &empty.Empty{}); err != nil {
+func (backend *GrpcBackend) conn() (*grpc.ClientConn, error) {
+	managed, err := backend.conns.Get(backend.Profile())
+	if err != nil {
 		return nil, err
-	} else {
-		return res.ProxyList, err
 	}
+	return managed.Conn(), nil
 }
 
-func SetProxy(proxy string) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
-	}
+func (backend *GrpcBackend) ListUrl() (urls []string, err error) {
+	err = backend.invoke("GetAll", true, func(ctx context.Context, conn *grpc.ClientConn) error {
+		res, err := httpMonitorRpc.NewUrlServiceClient(conn).GetAll(ctx, &empty.Empty{})
+		if err == nil {
+			urls = res.Urls
+		}
+		return err
+	})
+	return
+}
 
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
+func (backend *GrpcBackend) ListUrlInterval() (urlInterval map[string]int32, err error) {
+	err = backend.invoke("GetAllDomainAndInterval", true, func(ctx context.Context, conn *grpc.ClientConn) error {
+		res, err := httpMonitorRpc.NewUrlServiceClient(conn).GetAllDomainAndInterval(ctx, &empty.Empty{})
+		if err == nil {
+			urlInterval = res.UrlInterval
+		}
+		return err
+	})
+	return
+}
 
-	_, err := rpcClient.SetProxy(context.Background(), &httpMonitorRpc.ProxyRequest{Proxy: proxy})
-	return err
+func (backend *GrpcBackend) SetUrl(url string, interval int32) error {
+	return backend.invoke("SetUrl", false, func(ctx context.Context, conn *grpc.ClientConn) error {
+		_, err := httpMonitorRpc.NewUrlServiceClient(conn).SetUrl(ctx, &httpMonitorRpc.UrlRequest{Url: url, Interval: interval})
+		return err
+	})
 }
 
-func DeleteProxy(proxy string) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
-	}
+func (backend *GrpcBackend) DeleteUrl(url string) error {
+	return backend.invoke("DeleteUrl", false, func(ctx context.Context, conn *grpc.ClientConn) error {
+		_, err := httpMonitorRpc.NewUrlServiceClient(conn).DeleteUrl(ctx, &httpMonitorRpc.UrlRequest{Url: url})
+		return err
+	})
+}
 
-	rpcClient := httpMonitorRpc.NewUrlServiceClient(conn)
+func (backend *GrpcBackend) ListProxy() (proxyList []string, err error) {
+	err = backend.invoke("GetAllProxy", true, func(ctx context.Context, conn *grpc.ClientConn) error {
+		res, err := httpMonitorRpc.NewUrlServiceClient(conn).GetAllProxy(ctx, &empty.Empty{})
+		if err == nil {
+			proxyList = res.ProxyList
+		}
+		return err
+	})
+	return
+}
 
-	_, err := rpcClient.DeleteProxy(context.Background(), &httpMonitorRpc.ProxyRequest{Proxy: proxy})
-	return err
+func (backend *GrpcBackend) SetProxy(proxy string) error {
+	return backend.invoke("SetProxy", false, func(ctx context.Context, conn *grpc.ClientConn) error {
+		_, err := httpMonitorRpc.NewUrlServiceClient(conn).SetProxy(ctx, &httpMonitorRpc.ProxyRequest{Proxy: proxy})
+		return err
+	})
 }
 
-func GetRpcConn() (*grpc.ClientConn, error) {
-	tlsCredentials, err := loadClientTLSCredentials()
+func (backend *GrpcBackend) DeleteProxy(proxy string) error {
+	return backend.invoke("DeleteProxy", false, func(ctx context.Context, conn *grpc.ClientConn) error {
+		_, err := httpMonitorRpc.NewUrlServiceClient(conn).DeleteProxy(ctx, &httpMonitorRpc.ProxyRequest{Proxy: proxy})
+		return err
+	})
+}
+
+func GetRpcConn(profile Profile) (*grpc.ClientConn, error) {
+	tlsCredentials, err := loadClientTLSCredentials(profile)
 	if err != nil {
 		log.Error().Caller().Msg("cannot load TLS credentials: " + err.Error())
 		return nil, err
@@ -169,7 +152,11 @@ func GetRpcConn() (*grpc.ClientConn, error) {
 		log.Error().Caller().Msg("credentials.NewClientTLSFromFile err: " + err.Error())
 		return nil, err
 	}
-	conn, err := grpc.Dial(address, grpc.WithTransportCredentials(tlsCredentials))
+	conn, err := grpc.Dial(profile.Address,
+		grpc.WithTransportCredentials(tlsCredentials),
+		grpc.WithChainUnaryInterceptor(unaryLogInterceptor),
+		grpc.WithChainStreamInterceptor(streamLogInterceptor),
+	)
 	if err != nil {
 		log.Error().Caller().Msg("did not connect: " + err.Error())
 		return nil, err
@@ -178,78 +165,58 @@ func GetRpcConn() (*grpc.ClientConn, error) {
 	return conn, nil
 }
 
-func StartMonitor(monitorQueue *MonitorQueue) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
+// OpenMonitor 打开监控结果流 流长期存在 不设置超时
+func (backend *GrpcBackend) OpenMonitor(ctx context.Context) (MonitorStream, error) {
+	conn, err := backend.conn()
+	if err != nil {
+		return nil, err
 	}
 
 	rpcClient := httpMonitorRpc.NewMonitorServerClient(conn)
-	if stream, err := rpcClient.Start(context.Background(), &httpMonitorRpc.MonitorRequest{}); err != nil {
-		return err
-	} else {
-		go func() {
-			monitorQueue.m.Lock()
-			monitorQueue.Running = true
-			defer func() {
-				monitorQueue.m.Unlock()
-				monitorQueue.Running = false
-			}()
-			for {
-				//Recv() 方法接收服务端消息，默认每次Recv()最大消息长度为`1024*1024*4`bytes(4M)
-				res, err := stream.Recv()
-
-				// 判断消息流是否已经结束
-				if err == io.EOF {
-					log.Debug().Caller().Msg(err.Error())
-					break
-				}
-
-				if err != nil {
-					log.Error().Caller().Msg(err.Error())
-					break
-				}
-
-				monitorQueue.Queue <- res
-			}
-		}()
-
+	stream, err := rpcClient.Start(ctx, &httpMonitorRpc.MonitorRequest{})
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return stream, nil
 }
 
-func StopMonitor(monitorQueue *MonitorQueue) error {
-	poolConn := grpcConnPool.Get()
-	conn, ok := poolConn.(*grpc.ClientConn)
-	defer grpcConnPool.Put(poolConn)
-	if !ok {
-		return errors.New("from pool get conn failed")
-	}
-
-	rpcClient := httpMonitorRpc.NewMonitorServerClient(conn)
-	_, err := rpcClient.Stop(context.Background(), &empty.Empty{})
-	return err
+func (backend *GrpcBackend) StopMonitor() error {
+	return backend.invoke("Stop", false, func(ctx context.Context, conn *grpc.ClientConn) error {
+		_, err := httpMonitorRpc.NewMonitorServerClient(conn).Stop(ctx, &empty.Empty{})
+		return err
+	})
 }
 
-func loadClientTLSCredentials() (credentials.TransportCredentials, error) {
+func loadClientTLSCredentials(profile Profile) (credentials.TransportCredentials, error) {
+	rootPem := config.GetRoot()
+	if profile.CaFile != "" {
+		var err error
+		if rootPem, err = os.ReadFile(profile.CaFile); err != nil {
+			return nil, err
+		}
+	}
 
 	certPool := x509.NewCertPool()
-	if !certPool.AppendCertsFromPEM(config.GetRoot()) {
+	if !certPool.AppendCertsFromPEM(rootPem) {
 		return nil, fmt.Errorf("failed to add server CA's certificate")
 	}
 
 	// Load client's certificate and private key
-	clientCert, err := tls.X509KeyPair(config.GetClientCertChain(), config.GetClientPrivateKey())
+	var clientCert tls.Certificate
+	var err error
+	if profile.CertFile != "" {
+		clientCert, err = tls.LoadX509KeyPair(profile.CertFile, profile.KeyFile)
+	} else {
+		clientCert, err = tls.X509KeyPair(config.GetClientCertChain(), config.GetClientPrivateKey())
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	// Create the credentials and return it
 	tlsConfig := &tls.Config{
-		ServerName:   "test.com", //生成的证书通用名称 必须一致
+		ServerName:   profile.ServerName, //生成的证书通用名称 必须一致
 		Certificates: []tls.Certificate{clientCert},
 		RootCAs:      certPool,
 	}
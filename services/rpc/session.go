@@ -0,0 +1,267 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2/data/binding"
+	"github.com/rs/zerolog/log"
+)
+
+type SessionState int
+
+const (
+	SessionIdle SessionState = iota
+	SessionStarting
+	SessionRunning
+	SessionReconnecting
+	SessionStopping
+	SessionFailed
+)
+
+func (state SessionState) String() string {
+	switch state {
+	case SessionIdle:
+		return "未启动"
+	case SessionStarting:
+		return "启动中"
+	case SessionRunning:
+		return "运行中"
+	case SessionReconnecting:
+		return "重连中"
+	case SessionStopping:
+		return "停止中"
+	case SessionFailed:
+		return "失败"
+	}
+	return "未知"
+}
+
+// Active 监控流已打开或正在打开
+func (state SessionState) Active() bool {
+	return state == SessionStarting || state == SessionRunning || state == SessionReconnecting
+}
+
+// sessionTransitions 允许的状态变化
+var sessionTransitions = map[SessionState][]SessionState{
+	SessionIdle:         {SessionStarting},
+	SessionStarting:     {SessionRunning, SessionFailed, SessionStopping},
+	SessionRunning:      {SessionReconnecting, SessionStopping, SessionIdle},
+	SessionReconnecting: {SessionRunning, SessionFailed, SessionStopping},
+	SessionStopping:     {SessionIdle},
+	SessionFailed:       {SessionStarting},
+}
+
+// Backoff 重连间隔 从Initial开始每次失败翻倍 最大为Max
+type Backoff struct {
+	Initial time.Duration
+	Max     time.Duration
+	// MaxAttempts 失败后最多重试的次数 0表示不重试 会话重连与grpc调用重试含义相同
+	MaxAttempts int
+}
+
+var DefaultBackoff = Backoff{Initial: time.Second, Max: time.Minute, MaxAttempts: 10}
+
+// Delay 第attempt次重连前的等待时间 attempt从1开始
+func (backoff Backoff) Delay(attempt int) time.Duration {
+	delay := backoff.Initial
+	for i := 1; i < attempt && delay < backoff.Max; i++ {
+		delay *= 2
+	}
+	if delay > backoff.Max {
+		delay = backoff.Max
+	}
+
+	return delay
+}
+
+// Session 一个服务的监控会话 负责打开监控流 断开后按Backoff自动重连
+type Session struct {
+	Backoff Backoff
+
+	backend   MonitorBackend
+	stateText binding.String
+
+	m      sync.Mutex
+	state  SessionState
+	err    error
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+func NewSession(backend MonitorBackend) *Session {
+	session := &Session{Backoff: DefaultBackoff, backend: backend, stateText: binding.NewString()}
+	_ = session.stateText.Set(SessionIdle.String())
+
+	return session
+}
+
+func (session *Session) Name() string {
+	return session.backend.Name()
+}
+
+func (session *Session) State() SessionState {
+	session.m.Lock()
+	defer session.m.Unlock()
+	return session.state
+}
+
+// StateText 状态文字 可直接绑定到label 也可监听状态变化
+func (session *Session) StateText() binding.String {
+	return session.stateText
+}
+
+// Err 最近一次打开或接收失败的错误
+func (session *Session) Err() error {
+	session.m.Lock()
+	defer session.m.Unlock()
+	return session.err
+}
+
+// Start 打开监控流 结果发布到bus 首次打开失败直接进入Failed并返回错误
+func (session *Session) Start(bus *EventBus) error {
+	session.m.Lock()
+	if err := session.transition(SessionStarting); err != nil {
+		session.m.Unlock()
+		return err
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	session.cancel = cancel
+	session.done = done
+	session.err = nil
+	session.m.Unlock()
+
+	stream, err := session.backend.OpenMonitor(ctx)
+	if err != nil {
+		session.m.Lock()
+		session.err = err
+		_ = session.transition(SessionFailed)
+		session.m.Unlock()
+		cancel()
+		close(done)
+		return err
+	}
+
+	session.m.Lock()
+	_ = session.transition(SessionRunning)
+	session.m.Unlock()
+	go session.run(ctx, stream, bus, done)
+
+	return nil
+}
+
+// Stop 通知服务停止监控并关闭监控流
+func (session *Session) Stop() error {
+	session.m.Lock()
+	if !session.state.Active() {
+		session.m.Unlock()
+		return nil
+	}
+	_ = session.transition(SessionStopping)
+	cancel, done := session.cancel, session.done
+	session.m.Unlock()
+
+	err := session.backend.StopMonitor()
+	cancel()
+	<-done
+
+	session.m.Lock()
+	_ = session.transition(SessionIdle)
+	session.m.Unlock()
+
+	return err
+}
+
+func (session *Session) run(ctx context.Context, stream MonitorStream, bus *EventBus, done chan struct{}) {
+	defer close(done)
+
+	attempt := 0
+	for {
+		if stream != nil {
+			received, err := session.receive(stream, bus)
+			if ctx.Err() != nil {
+				return
+			}
+			if received {
+				attempt = 0
+			}
+			if err == io.EOF {
+				//服务端主动结束 例如其他客户端停止了监控
+				log.Debug().Caller().Str("source", session.Name()).Msg("monitor stream closed by server")
+				session.m.Lock()
+				_ = session.transition(SessionIdle)
+				session.m.Unlock()
+				return
+			}
+			log.Error().Caller().Str("source", session.Name()).Msg(err.Error())
+			session.m.Lock()
+			session.err = err
+			_ = session.transition(SessionReconnecting)
+			session.m.Unlock()
+		}
+
+		attempt++
+		if attempt > session.Backoff.MaxAttempts {
+			session.m.Lock()
+			_ = session.transition(SessionFailed)
+			session.m.Unlock()
+			return
+		}
+
+		select {
+		case <-time.After(session.Backoff.Delay(attempt)):
+		case <-ctx.Done():
+			return
+		}
+
+		var err error
+		if stream, err = session.backend.OpenMonitor(ctx); err != nil {
+			log.Error().Caller().Str("source", session.Name()).Int("attempt", attempt).Msg(err.Error())
+			session.m.Lock()
+			session.err = err
+			session.m.Unlock()
+			stream = nil
+			continue
+		}
+
+		session.m.Lock()
+		_ = session.transition(SessionRunning)
+		session.m.Unlock()
+	}
+}
+
+// receive 读取监控流直到出错 received表示是否收到过结果
+func (session *Session) receive(stream MonitorStream, bus *EventBus) (received bool, err error) {
+	for {
+		//Recv() 方法接收服务端消息，默认每次Recv()最大消息长度为`1024*1024*4`bytes(4M)
+		res, err := stream.Recv()
+		if err != nil {
+			return received, err
+		}
+		received = true
+
+		//服务端空闲时每秒发送一次空结果作为心跳
+		if res.Url == "" {
+			continue
+		}
+
+		bus.Publish(&MonitorResult{Source: session.Name(), Time: time.Now(), MonitorResponse: res})
+	}
+}
+
+// transition 调用前必须持有锁
+func (session *Session) transition(to SessionState) error {
+	for _, state := range sessionTransitions[session.state] {
+		if state == to {
+			session.state = to
+			_ = session.stateText.Set(to.String())
+			return nil
+		}
+	}
+
+	return errors.New(session.Name() + "监控" + session.state.String() + " 不能切换到" + to.String())
+}
@@ -0,0 +1,113 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	httpMonitorRpc "github.com/flyflyhe/httpMonitor/rpc"
+	"github.com/stretchr/testify/assert"
+)
+
+// flakyBackend 每个流发送一个结果后断开 openErrs为后续打开流依次返回的错误
+type flakyBackend struct {
+	*MemoryBackend
+	m        sync.Mutex
+	opened   int
+	openErrs []error
+}
+
+type flakyStream struct {
+	ctx  context.Context
+	sent bool
+}
+
+func (stream *flakyStream) Recv() (*httpMonitorRpc.MonitorResponse, error) {
+	if !stream.sent {
+		stream.sent = true
+		return &httpMonitorRpc.MonitorResponse{Url: "https://www.baidu.com", Result: map[string]string{"": "success"}}, nil
+	}
+	<-time.After(10 * time.Millisecond)
+	if stream.ctx.Err() != nil {
+		return nil, stream.ctx.Err()
+	}
+	return nil, errors.New("transport is closing")
+}
+
+func (backend *flakyBackend) OpenMonitor(ctx context.Context) (MonitorStream, error) {
+	backend.m.Lock()
+	defer backend.m.Unlock()
+	backend.opened++
+	if len(backend.openErrs) > 0 {
+		err := backend.openErrs[0]
+		backend.openErrs = backend.openErrs[1:]
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &flakyStream{ctx: ctx}, nil
+}
+
+func (backend *flakyBackend) StopMonitor() error {
+	return nil
+}
+
+func waitState(t *testing.T, session *Session, state SessionState) {
+	deadline := time.Now().Add(time.Second)
+	for session.State() != state {
+		if time.Now().After(deadline) {
+			t.Fatalf("session state %s, want %s", session.State(), state)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{Initial: time.Second, Max: 5 * time.Second}
+	assert.Equal(t, time.Second, backoff.Delay(1))
+	assert.Equal(t, 2*time.Second, backoff.Delay(2))
+	assert.Equal(t, 4*time.Second, backoff.Delay(3))
+	assert.Equal(t, 5*time.Second, backoff.Delay(4))
+	assert.Equal(t, 5*time.Second, backoff.Delay(100))
+}
+
+func TestSessionReconnect(t *testing.T) {
+	backend := &flakyBackend{MemoryBackend: NewMemoryBackend()}
+	session := NewSession(backend)
+	session.Backoff = Backoff{Initial: time.Millisecond, Max: 4 * time.Millisecond, MaxAttempts: 2}
+	bus := NewEventBus()
+	queue := bus.Subscribe("test", 100)
+
+	//首次打开失败直接进入Failed
+	backend.openErrs = []error{errors.New("connection refused")}
+	assert.NotNil(t, session.Start(bus))
+	assert.Equal(t, SessionFailed, session.State())
+
+	//流断开后自动重连 每次重连都能收到结果
+	assert.Nil(t, session.Start(bus))
+	assert.NotNil(t, session.Start(bus))
+	for i := 0; i < 3; i++ {
+		select {
+		case res := <-queue.C:
+			assert.Equal(t, "memory", res.Source)
+		case <-time.After(time.Second):
+			t.Fatal("no monitor result")
+		}
+	}
+	assert.Nil(t, session.Stop())
+	assert.Equal(t, SessionIdle, session.State())
+	text, _ := session.StateText().Get()
+	assert.Equal(t, SessionIdle.String(), text)
+
+	//连续重连失败超过MaxAttempts后进入Failed
+	backend.m.Lock()
+	backend.openErrs = []error{nil, errors.New("unavailable"), errors.New("unavailable"), errors.New("unavailable")}
+	backend.m.Unlock()
+	assert.Nil(t, session.Start(bus))
+	<-queue.C
+	waitState(t, session, SessionFailed)
+	assert.Equal(t, "unavailable", session.Err().Error())
+	assert.Nil(t, session.Stop())
+}
@@ -0,0 +1,95 @@
+package rpc
+
+import (
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 检测间隔范围与未指定时的默认值
+const (
+	MinInterval     = 100 * time.Millisecond
+	MaxInterval     = 24 * time.Hour
+	DefaultInterval = 30 * time.Second
+)
+
+// ValidateUrl 只允许http与https
+func ValidateUrl(rawUrl string) error {
+	if rawUrl == "" {
+		return errors.New("url不能为空")
+	}
+	u, err := url.Parse(rawUrl)
+	if err != nil {
+		return errors.New("url格式错误:" + err.Error())
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return errors.New("url必须以http://或https://开头")
+	}
+	if u.Hostname() == "" {
+		return errors.New("url缺少域名")
+	}
+
+	return nil
+}
+
+// ParseInterval 解析检测间隔 纯数字为毫秒 也支持"30s" "1m30s"这样的时长 返回毫秒
+func ParseInterval(text string) (int32, error) {
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return 0, errors.New("间隔时间不能为空")
+	}
+
+	var d time.Duration
+	if ms, err := strconv.ParseInt(text, 10, 64); err == nil {
+		d = time.Duration(ms) * time.Millisecond
+	} else if d, err = time.ParseDuration(text); err != nil {
+		return 0, errors.New("间隔时间格式错误 eg:500 30s 1m")
+	}
+
+	if d < MinInterval || d > MaxInterval {
+		return 0, errors.New("间隔时间必须在" + MinInterval.String() + "到" + MaxInterval.String() + "之间")
+	}
+
+	return int32(d / time.Millisecond), nil
+}
+
+// FormatInterval 毫秒转为时长文字 如"30s"
+func FormatInterval(interval int32) string {
+	return (time.Duration(interval) * time.Millisecond).String()
+}
+
+// UpdateUrl 修改url的检测间隔 地址变化时先删除旧地址再添加新地址 添加失败时恢复旧地址
+func UpdateUrl(backend MonitorBackend, oldUrl, newUrl string, interval int32) error {
+	if err := ValidateUrl(newUrl); err != nil {
+		return err
+	}
+	if oldUrl == newUrl {
+		return backend.SetUrl(newUrl, interval)
+	}
+
+	urls, err := backend.ListUrlInterval()
+	if err != nil {
+		return err
+	}
+	oldInterval, ok := urls[oldUrl]
+	if !ok {
+		return errors.New("地址不存在:" + oldUrl)
+	}
+	if _, ok := urls[newUrl]; ok {
+		return errors.New("地址已存在:" + newUrl)
+	}
+
+	if err = backend.DeleteUrl(oldUrl); err != nil {
+		return err
+	}
+	if err = backend.SetUrl(newUrl, interval); err != nil {
+		if rollbackErr := backend.SetUrl(oldUrl, oldInterval); rollbackErr != nil {
+			return errors.New("修改失败:" + err.Error() + " 恢复原地址失败:" + rollbackErr.Error())
+		}
+		return errors.New("修改失败 已恢复原地址:" + err.Error())
+	}
+
+	return nil
+}
@@ -0,0 +1,63 @@
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInterval(t *testing.T) {
+	cases := map[string]int32{"500": 500, "30s": 30000, "1m30s": 90000, " 2h ": 7200000}
+	for text, interval := range cases {
+		got, err := ParseInterval(text)
+		assert.Nil(t, err, text)
+		assert.Equal(t, interval, got, text)
+	}
+	for _, text := range []string{"", "abc", "10", "-5s", "25h"} {
+		_, err := ParseInterval(text)
+		assert.NotNil(t, err, text)
+	}
+	assert.Equal(t, "1m30s", FormatInterval(90000))
+}
+
+func TestValidateUrl(t *testing.T) {
+	assert.Nil(t, ValidateUrl("https://www.baidu.com/s?wd=1"))
+	assert.NotNil(t, ValidateUrl(""))
+	assert.NotNil(t, ValidateUrl("www.baidu.com"))
+	assert.NotNil(t, ValidateUrl("ftp://www.baidu.com"))
+	assert.NotNil(t, ValidateUrl("http://"))
+}
+
+// rejectBackend SetUrl拒绝指定地址
+type rejectBackend struct {
+	*MemoryBackend
+	reject string
+}
+
+func (backend *rejectBackend) SetUrl(url string, interval int32) error {
+	if url == backend.reject {
+		return errors.New("rejected")
+	}
+	return backend.MemoryBackend.SetUrl(url, interval)
+}
+
+func TestUpdateUrl(t *testing.T) {
+	backend := &rejectBackend{MemoryBackend: NewMemoryBackend(), reject: "https://bad.example.com"}
+	assert.Nil(t, backend.SetUrl("https://www.baidu.com", 1000))
+	assert.Nil(t, backend.SetUrl("https://www.google.com", 1000))
+
+	assert.Nil(t, UpdateUrl(backend, "https://www.baidu.com", "https://www.baidu.com", 2000))
+	assert.Nil(t, UpdateUrl(backend, "https://www.baidu.com", "https://baidu.com", 3000))
+	urls, _ := backend.ListUrlInterval()
+	assert.Equal(t, map[string]int32{"https://baidu.com": 3000, "https://www.google.com": 1000}, urls)
+
+	assert.EqualError(t, UpdateUrl(backend, "https://baidu.com", "https://www.google.com", 1000), "地址已存在:https://www.google.com")
+	assert.NotNil(t, UpdateUrl(backend, "https://none.example.com", "https://new.example.com", 1000))
+
+	//添加失败时恢复原地址和原间隔
+	err := UpdateUrl(backend, "https://baidu.com", "https://bad.example.com", 1000)
+	assert.EqualError(t, err, "修改失败 已恢复原地址:rejected")
+	urls, _ = backend.ListUrlInterval()
+	assert.Equal(t, map[string]int32{"https://baidu.com": 3000, "https://www.google.com": 1000}, urls)
+}
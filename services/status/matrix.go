@@ -0,0 +1,269 @@
+package status
+
+import (
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+)
+
+// Column 矩阵的一列 为某个服务上的某个代理 Proxy为空表示直连
+type Column struct {
+	Source string
+	Proxy  string
+}
+
+// Labeler 代理的显示名称 如代理标签 为nil时显示隐藏密码的代理地址
+type Labeler func(proxy string) string
+
+// Proxy 代理显示名称 为空表示直连
+func (labeler Labeler) Proxy(raw string) string {
+	switch {
+	case raw == "":
+		return "直连"
+	case labeler != nil:
+		return labeler(raw)
+	}
+	return proxy.Redact(raw)
+}
+
+// Title 列标题 只有一个服务时省略服务名
+func (labeler Labeler) Title(column Column, multiSource bool) string {
+	title := labeler.Proxy(column.Proxy)
+	if multiSource {
+		title = "[" + column.Source + "]" + title
+	}
+	return title
+}
+
+// Cell url在某列上的最近一次结果
+type Cell struct {
+	result.Result
+	Time time.Time
+}
+
+// Matrix url×代理的最新状态 行为url 列为代理 结果到达时原地更新
+type Matrix struct {
+	m       sync.RWMutex
+	urls    []string
+	columns []Column
+	cells   map[string]map[Column]Cell
+}
+
+func NewMatrix() *Matrix {
+	return &Matrix{cells: make(map[string]map[Column]Cell)}
+}
+
+// Update 写入一次监控结果 返回是否新增了行或列
+func (matrix *Matrix) Update(res *rpc.MonitorResult) (grown bool) {
+	if res == nil || res.MonitorResponse == nil || res.Url == "" {
+		return false
+	}
+	t := res.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+
+	matrix.m.Lock()
+	defer matrix.m.Unlock()
+
+	row, ok := matrix.cells[res.Url]
+	if !ok {
+		row = make(map[Column]Cell)
+		matrix.cells[res.Url] = row
+		matrix.urls = append(matrix.urls, res.Url)
+		sort.Strings(matrix.urls)
+		grown = true
+	}
+	for proxy, v := range res.Result {
+		column := Column{Source: res.Source, Proxy: proxy}
+		if !matrix.hasColumn(column) {
+			matrix.columns = append(matrix.columns, column)
+			sortColumns(matrix.columns)
+			grown = true
+		}
+		row[column] = Cell{Result: result.Parse(v), Time: t}
+	}
+
+	return
+}
+
+func (matrix *Matrix) hasColumn(column Column) bool {
+	for _, c := range matrix.columns {
+		if c == column {
+			return true
+		}
+	}
+	return false
+}
+
+// sortColumns 按服务排序 同一服务直连在前
+func sortColumns(columns []Column) {
+	sort.Slice(columns, func(i, j int) bool {
+		if columns[i].Source != columns[j].Source {
+			return columns[i].Source < columns[j].Source
+		}
+		return columns[i].Proxy < columns[j].Proxy
+	})
+}
+
+func (matrix *Matrix) Urls() []string {
+	matrix.m.RLock()
+	defer matrix.m.RUnlock()
+	return append([]string(nil), matrix.urls...)
+}
+
+func (matrix *Matrix) Columns() []Column {
+	matrix.m.RLock()
+	defer matrix.m.RUnlock()
+	return append([]Column(nil), matrix.columns...)
+}
+
+// MultiSource 是否有多个服务的结果
+func (matrix *Matrix) MultiSource() bool {
+	matrix.m.RLock()
+	defer matrix.m.RUnlock()
+	for _, column := range matrix.columns {
+		if column.Source != matrix.columns[0].Source {
+			return true
+		}
+	}
+	return false
+}
+
+// Cell ok为false表示还没有结果
+func (matrix *Matrix) Cell(url string, column Column) (cell Cell, ok bool) {
+	matrix.m.RLock()
+	defer matrix.m.RUnlock()
+	cell, ok = matrix.cells[url][column]
+	return
+}
+
+// Health 一组url的汇总状态
+type Health struct {
+	Ok      int
+	Failed  int
+	Pending int
+}
+
+func (health Health) Total() int {
+	return health.Ok + health.Failed + health.Pending
+}
+
+func (health Health) String() string {
+	text := "正常" + strconv.Itoa(health.Ok) + " 故障" + strconv.Itoa(health.Failed)
+	if health.Pending > 0 {
+		text += " 未检测" + strconv.Itoa(health.Pending)
+	}
+	return text
+}
+
+// Health 汇总满足match的url 任一列失败即为故障 match为nil时汇总全部
+func (matrix *Matrix) Health(urls []string, match func(url string) bool) Health {
+	matrix.m.RLock()
+	defer matrix.m.RUnlock()
+
+	var health Health
+	for _, url := range urls {
+		if match != nil && !match(url) {
+			continue
+		}
+		row := matrix.cells[url]
+		if len(row) == 0 {
+			health.Pending++
+			continue
+		}
+		failed := false
+		for _, cell := range row {
+			if !cell.OK() {
+				failed = true
+				break
+			}
+		}
+		if failed {
+			health.Failed++
+		} else {
+			health.Ok++
+		}
+	}
+
+	return health
+}
+
+// Retain 删除source上已不存在的url与代理的结果 直连与其他服务的结果不变 返回是否删除了行或列
+func (matrix *Matrix) Retain(source string, urls, proxies []string) (shrunk bool) {
+	keepUrl := make(map[string]bool, len(urls))
+	for _, url := range urls {
+		keepUrl[url] = true
+	}
+	keepProxy := map[string]bool{"": true}
+	for _, proxy := range proxies {
+		keepProxy[proxy] = true
+	}
+
+	matrix.m.Lock()
+	defer matrix.m.Unlock()
+
+	columns := matrix.columns[:0]
+	for _, column := range matrix.columns {
+		if column.Source == source && !keepProxy[column.Proxy] {
+			for _, row := range matrix.cells {
+				delete(row, column)
+			}
+			shrunk = true
+			continue
+		}
+		columns = append(columns, column)
+	}
+	matrix.columns = columns
+
+	rows := matrix.urls[:0]
+	for _, url := range matrix.urls {
+		row := matrix.cells[url]
+		if !keepUrl[url] {
+			for column := range row {
+				if column.Source == source {
+					delete(row, column)
+				}
+			}
+			if len(row) == 0 {
+				delete(matrix.cells, url)
+				shrunk = true
+				continue
+			}
+		}
+		rows = append(rows, url)
+	}
+	matrix.urls = rows
+
+	return
+}
+
+// Reset 清空全部结果
+func (matrix *Matrix) Reset() {
+	matrix.m.Lock()
+	defer matrix.m.Unlock()
+	matrix.urls = nil
+	matrix.columns = nil
+	matrix.cells = make(map[string]map[Column]Cell)
+}
+
+// Since 距上次检测的时间 如"3秒前"
+func Since(t time.Time, now time.Time) string {
+	d := now.Sub(t)
+	switch {
+	case d < time.Second:
+		return "刚刚"
+	case d < time.Minute:
+		return strconv.Itoa(int(d/time.Second)) + "秒前"
+	case d < time.Hour:
+		return strconv.Itoa(int(d/time.Minute)) + "分钟前"
+	case d < 24*time.Hour:
+		return strconv.Itoa(int(d/time.Hour)) + "小时前"
+	}
+	return strconv.Itoa(int(d/(24*time.Hour))) + "天前"
+}
@@ -0,0 +1,431 @@
+package statuspage
+
+import (
+	"math"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/history"
+	"github.com/flyflyhe/httpMonitorGui/services/result"
+)
+
+const (
+	// DefaultDays 可用率条显示的天数
+	DefaultDays = 90
+	// DefaultIncidents 最多显示的故障数
+	DefaultIncidents = 10
+	// DefaultIncidentDays 最近故障与当前状态只读取这些天的原始记录
+	DefaultIncidentDays = 7
+	// DefaultTitle 页面标题
+	DefaultTitle = "服务状态"
+)
+
+// State 一次检测 一个url或整个页面的状态 值越大越严重
+type State int
+
+const (
+	StateUnknown State = iota
+	StateOk
+	StateDegraded
+	StateDown
+)
+
+func (state State) String() string {
+	switch state {
+	case StateOk:
+		return "正常"
+	case StateDegraded:
+		return "部分异常"
+	case StateDown:
+		return "故障"
+	}
+	return "暂无数据"
+}
+
+// Class 页面中的css类名
+func (state State) Class() string {
+	switch state {
+	case StateOk:
+		return "ok"
+	case StateDegraded:
+		return "degraded"
+	case StateDown:
+		return "down"
+	}
+	return "unknown"
+}
+
+// Headline 页面顶部的整体状态
+func (state State) Headline() string {
+	switch state {
+	case StateOk:
+		return "全部服务正常"
+	case StateDegraded:
+		return "部分服务异常"
+	case StateDown:
+		return "服务故障"
+	}
+	return "暂无数据"
+}
+
+// Options 为零值的字段使用默认值
+type Options struct {
+	Title        string
+	Days         int
+	Incidents    int
+	IncidentDays int
+}
+
+func (opts Options) withDefaults() Options {
+	if opts.Title == "" {
+		opts.Title = DefaultTitle
+	}
+	if opts.Days <= 0 {
+		opts.Days = DefaultDays
+	}
+	if opts.Incidents <= 0 {
+		opts.Incidents = DefaultIncidents
+	}
+	if opts.IncidentDays <= 0 {
+		opts.IncidentDays = DefaultIncidentDays
+	}
+	return opts
+}
+
+// Day 一天的检测汇总 Up为未完全失败的检测次数
+type Day struct {
+	Date     time.Time
+	Checks   int
+	Up       int
+	Degraded int
+}
+
+// Uptime 没有检测时为1
+func (day Day) Uptime() float64 {
+	if day.Checks == 0 {
+		return 1
+	}
+	return float64(day.Up) / float64(day.Checks)
+}
+
+// State 全部正常为正常 可用率不低于99%为部分异常 否则为故障
+func (day Day) State() State {
+	switch {
+	case day.Checks == 0:
+		return StateUnknown
+	case day.Up == day.Checks && day.Degraded == 0:
+		return StateOk
+	case day.Uptime() >= 0.99:
+		return StateDegraded
+	}
+	return StateDown
+}
+
+// Url 一个url的当前状态与每天的可用率 Days按日期升序
+type Url struct {
+	Url       string
+	State     State
+	LastCheck time.Time
+	Days      []Day
+}
+
+// Uptime 全部天数的可用率 没有检测时为1
+func (u Url) Uptime() float64 {
+	checks, up := 0, 0
+	for _, day := range u.Days {
+		checks += day.Checks
+		up += day.Up
+	}
+	if checks == 0 {
+		return 1
+	}
+	return float64(up) / float64(checks)
+}
+
+// Group 一个分组 状态为组内最严重的url状态
+type Group struct {
+	Name  string
+	State State
+	Urls  []Url
+}
+
+// Incident 连续的异常检测 End为零值表示仍未恢复
+type Incident struct {
+	Url   string
+	Group string
+	State State
+	Start time.Time
+	End   time.Time
+	// Cause 出现次数最多的失败原因 如超时 HTTP 502
+	Cause  string
+	causes map[string]int
+}
+
+func (incident Incident) Ongoing() bool {
+	return incident.End.IsZero()
+}
+
+// Duration 未恢复时计算到now
+func (incident Incident) Duration(now time.Time) time.Duration {
+	if incident.Ongoing() {
+		return now.Sub(incident.Start)
+	}
+	return incident.End.Sub(incident.Start)
+}
+
+// Page 状态页内容 只包含url与检测结果 不包含代理地址与原始错误信息
+// url去掉账号密码与查询参数
+type Page struct {
+	Title     string
+	Generated time.Time
+	State     State
+	Groups    []Group
+	Incidents []Incident
+}
+
+// Build 生成状态页 只包含urls中的地址 按index分组
+// 可用率取最近opts.Days天的每日汇总 按本地日期 故障与当前状态取最近opts.IncidentDays天的原始记录
+func Build(store *history.Store, urls []string, index map[string]group.Meta, now time.Time, opts Options) (*Page, error) {
+	opts = opts.withDefaults()
+	now = now.In(time.Local)
+	builder := newBuilder(urls, now, opts.Days)
+	if len(urls) > 0 {
+		daily, err := store.Daily(urls, builder.first)
+		if err != nil {
+			return nil, err
+		}
+		builder.addDays(daily)
+
+		q := history.Query{Urls: urls, From: now.AddDate(0, 0, -opts.IncidentDays)}
+		if err = store.Each(q, builder.add); err != nil {
+			return nil, err
+		}
+	}
+
+	return builder.page(index, opts), nil
+}
+
+// check 一个服务在同一时间对一个url的全部代理的检测
+type check struct {
+	source string
+	time   time.Time
+	state  State
+	failed map[string]int
+}
+
+type urlBuilder struct {
+	url       Url
+	check     *check
+	last      map[string]State
+	open      *Incident
+	incidents []Incident
+}
+
+type builder struct {
+	now   time.Time
+	first time.Time
+	urls  map[string]*urlBuilder
+}
+
+func dayStart(t time.Time) time.Time {
+	year, month, day := t.Date()
+	return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+}
+
+func newBuilder(urls []string, now time.Time, days int) *builder {
+	b := &builder{now: now, first: dayStart(now).AddDate(0, 0, 1-days), urls: make(map[string]*urlBuilder, len(urls))}
+	for _, url := range urls {
+		u := &urlBuilder{url: Url{Url: url, Days: make([]Day, days)}, last: make(map[string]State)}
+		for i := range u.url.Days {
+			u.url.Days[i].Date = b.first.AddDate(0, 0, i)
+		}
+		b.urls[url] = u
+	}
+	return b
+}
+
+// addDays 每日汇总填入对应日期
+func (b *builder) addDays(daily map[string][]history.Day) {
+	for url, days := range daily {
+		u, ok := b.urls[url]
+		if !ok {
+			continue
+		}
+		for _, day := range days {
+			//夏令时时一天不是24小时 四舍五入
+			i := int(math.Round(day.Date.Sub(b.first).Hours() / 24))
+			if i >= 0 && i < len(u.url.Days) {
+				u.url.Days[i].Checks = day.Checks
+				u.url.Days[i].Up = day.Up
+				u.url.Days[i].Degraded = day.Degraded
+			}
+		}
+	}
+}
+
+// add 同一url的记录按时间升序到达 同一次检测的记录相邻
+func (b *builder) add(record history.Record) error {
+	u, ok := b.urls[record.Url]
+	if !ok {
+		return nil
+	}
+	if u.check != nil && (u.check.source != record.Source || !u.check.time.Equal(record.Time)) {
+		b.finish(u)
+	}
+	if u.check == nil {
+		u.check = &check{source: record.Source, time: record.Time, failed: make(map[string]int)}
+	}
+
+	r := result.Parse(record.Result)
+	switch {
+	case u.check.state == StateUnknown:
+		if r.OK() {
+			u.check.state = StateOk
+		} else {
+			u.check.state = StateDown
+		}
+	case u.check.state == StateOk && !r.OK(), u.check.state == StateDown && r.OK():
+		u.check.state = StateDegraded
+	}
+	if !r.OK() {
+		u.check.failed[r.String()]++
+	}
+
+	return nil
+}
+
+// finish 一次检测结束 计入当前状态与故障
+func (b *builder) finish(u *urlBuilder) {
+	c := u.check
+	u.check = nil
+
+	u.last[c.source] = c.state
+	if c.time.After(u.url.LastCheck) {
+		u.url.LastCheck = c.time
+	}
+
+	if c.state == StateOk {
+		if u.open != nil {
+			u.open.End = c.time
+			u.incidents = append(u.incidents, *u.open)
+			u.open = nil
+		}
+		return
+	}
+	if u.open == nil {
+		u.open = &Incident{Url: u.url.Url, State: c.state, Start: c.time, causes: make(map[string]int)}
+	}
+	if c.state > u.open.State {
+		u.open.State = c.state
+	}
+	for cause, n := range c.failed {
+		u.open.causes[cause] += n
+	}
+}
+
+func (b *builder) page(index map[string]group.Meta, opts Options) *Page {
+	page := &Page{Title: opts.Title, Generated: b.now}
+	groups := make(map[string]*Group)
+	for _, u := range b.urls {
+		if u.check != nil {
+			b.finish(u)
+		}
+		if u.open != nil {
+			u.incidents = append(u.incidents, *u.open)
+			u.open = nil
+		}
+		u.url.State = combine(u.last)
+
+		name := index[u.url.Url].GroupName()
+		u.url.Url = publicUrl(u.url.Url)
+		g, ok := groups[name]
+		if !ok {
+			g = &Group{Name: name}
+			groups[name] = g
+		}
+		g.Urls = append(g.Urls, u.url)
+		if u.url.State > g.State {
+			g.State = u.url.State
+		}
+		if u.url.State > page.State {
+			page.State = u.url.State
+		}
+		for _, incident := range u.incidents {
+			incident.Url = u.url.Url
+			incident.Group = name
+			incident.Cause = mostCommon(incident.causes)
+			page.Incidents = append(page.Incidents, incident)
+		}
+	}
+
+	for _, g := range groups {
+		sort.Slice(g.Urls, func(i, j int) bool {
+			return g.Urls[i].Url < g.Urls[j].Url
+		})
+		page.Groups = append(page.Groups, *g)
+	}
+	//未分组放在最后
+	sort.Slice(page.Groups, func(i, j int) bool {
+		if (page.Groups[i].Name == group.Ungrouped) != (page.Groups[j].Name == group.Ungrouped) {
+			return page.Groups[j].Name == group.Ungrouped
+		}
+		return page.Groups[i].Name < page.Groups[j].Name
+	})
+	sort.Slice(page.Incidents, func(i, j int) bool {
+		return page.Incidents[i].Start.After(page.Incidents[j].Start)
+	})
+	if len(page.Incidents) > opts.Incidents {
+		page.Incidents = page.Incidents[:opts.Incidents]
+	}
+
+	return page
+}
+
+// combine 各服务最近一次检测的状态 全部正常为正常 全部故障为故障
+func combine(last map[string]State) State {
+	state := StateUnknown
+	for _, s := range last {
+		switch {
+		case state == StateUnknown:
+			state = s
+		case state != s:
+			state = StateDegraded
+		}
+	}
+	return state
+}
+
+// publicUrl 页面公开 去掉url中的账号密码 查询参数与锚点
+func publicUrl(raw string) string {
+	u, err := url.Parse(raw)
+	if err != nil {
+		//无法解析时按字符截取 scheme://[userinfo@]host/path?query
+		raw = strings.SplitN(strings.SplitN(raw, "#", 2)[0], "?", 2)[0]
+		if i := strings.Index(raw, "://"); i >= 0 {
+			host := strings.SplitN(raw[i+3:], "/", 2)[0]
+			if at := strings.LastIndex(host, "@"); at >= 0 {
+				raw = raw[:i+3] + raw[i+3+at+1:]
+			}
+		}
+		return raw
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+func mostCommon(counts map[string]int) string {
+	best, n := "", 0
+	for k, v := range counts {
+		if v > n || v == n && k < best {
+			best, n = k, v
+		}
+	}
+	return best
+}
@@ -0,0 +1,179 @@
+package statuspage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/history"
+)
+
+// FileName 生成的页面文件名 任意静态web服务都可以直接托管
+const FileName = "index.html"
+
+var funcs = template.FuncMap{
+	"percent": func(v float64) string {
+		return fmt.Sprintf("%.2f%%", v*100)
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+	"datetime": func(t time.Time) string {
+		if t.IsZero() {
+			return "-"
+		}
+		return t.Format("2006-01-02 15:04")
+	},
+	"duration": func(d time.Duration) string {
+		return d.Round(time.Minute).String()
+	},
+}
+
+var page = template.Must(template.New(FileName).Funcs(funcs).Parse(`<!DOCTYPE html>
+<html lang="zh-CN">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta http-equiv="refresh" content="300">
+<title>{{.Title}}</title>
+<style>
+body{font-family:-apple-system,"PingFang SC","Microsoft YaHei",sans-serif;max-width:960px;margin:0 auto;padding:24px;color:#222;background:#fafafa}
+h1{font-size:24px}h2{font-size:18px;margin-top:32px}
+.banner{padding:16px;border-radius:6px;color:#fff;font-size:18px}
+.group{background:#fff;border:1px solid #e5e5e5;border-radius:6px;margin:16px 0;padding:8px 16px}
+.url{padding:8px 0;border-top:1px solid #f0f0f0}.url:first-of-type{border-top:none}
+.row{display:flex;justify-content:space-between;align-items:center;gap:8px;word-break:break-all}
+.bars{display:flex;gap:1px;height:28px;margin:6px 0 2px}.bars span{flex:1;border-radius:1px}
+.scale{display:flex;justify-content:space-between;color:#888;font-size:12px}
+.ok{background:#2fcc66;color:#2fcc66}.degraded{background:#f1c40f;color:#f1c40f}.down{background:#e74c3c;color:#e74c3c}.unknown{background:#d5d5d5;color:#999}
+.banner.ok,.banner.degraded,.banner.down,.banner.unknown{color:#fff}
+.state{background:none;font-weight:bold;white-space:nowrap}
+table{width:100%;border-collapse:collapse;background:#fff}td,th{border:1px solid #e5e5e5;padding:6px 8px;text-align:left;font-size:14px}
+footer{color:#888;font-size:12px;margin-top:32px}
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<div class="banner {{.State.Class}}">{{.State.Headline}}</div>
+{{range .Groups}}
+<div class="group">
+<div class="row"><h2>{{.Name}}</h2><span class="state {{.State.Class}}">{{.State}}</span></div>
+{{range .Urls}}
+<div class="url">
+<div class="row"><span>{{.Url}}</span><span class="state {{.State.Class}}">{{.State}}</span></div>
+<div class="bars">{{range .Days}}<span class="{{.State.Class}}" title="{{date .Date}} {{if .Checks}}可用率{{percent .Uptime}} 检测{{.Checks}}次{{else}}无数据{{end}}"></span>{{end}}</div>
+<div class="scale"><span>{{len .Days}}天前</span><span>可用率{{percent .Uptime}} 最近检测{{datetime .LastCheck}}</span><span>今天</span></div>
+</div>
+{{end}}
+</div>
+{{end}}
+<h2>最近故障</h2>
+{{if .Incidents}}
+<table>
+<tr><th>开始</th><th>地址</th><th>状态</th><th>原因</th><th>持续</th></tr>
+{{range .Incidents}}
+<tr><td>{{datetime .Start}}</td><td>[{{.Group}}] {{.Url}}</td><td><span class="state {{.State.Class}}">{{.State}}</span></td><td>{{.Cause}}</td><td>{{if .Ongoing}}未恢复 {{end}}{{duration (.Duration $.Generated)}}</td></tr>
+{{end}}
+</table>
+{{else}}
+<p>没有故障记录</p>
+{{end}}
+<footer>更新于 {{datetime .Generated}}</footer>
+</body>
+</html>
+`))
+
+// Render 输出完整的html页面 不依赖外部资源
+func Render(w io.Writer, p *Page) error {
+	return page.Execute(w, p)
+}
+
+// WriteDir 在dir下写入FileName 先写临时文件再重命名 web服务不会读到写了一半的页面
+func WriteDir(dir string, p *Page) error {
+	var buf bytes.Buffer
+	if err := Render(&buf, p); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, "."+FileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err = tmp.Close(); err != nil {
+		return err
+	}
+	if err = os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filepath.Join(dir, FileName))
+}
+
+// Generator 由历史记录生成状态页 Urls返回页面包含的地址 失败时使用历史记录中的地址
+// Groups为nil时全部未分组
+type Generator struct {
+	Store  *history.Store
+	Urls   func() ([]string, error)
+	Groups *group.Store
+	Options
+}
+
+// Generate 生成一次状态页写入dir
+func (generator *Generator) Generate(dir string) error {
+	urls, err := generator.Urls()
+	if err != nil {
+		//服务未启动或断开时使用历史记录中的url 仍能生成状态页
+		fyne.LogError("list url failed, use history", err)
+		if urls, err = generator.Store.Urls(); err != nil {
+			return err
+		}
+	}
+	var index map[string]group.Meta
+	if generator.Groups != nil {
+		index = generator.Groups.Index()
+	}
+	p, err := Build(generator.Store, urls, index, time.Now(), generator.Options)
+	if err != nil {
+		return err
+	}
+
+	return WriteDir(dir, p)
+}
+
+// DefaultInterval 定时生成的默认间隔
+const DefaultInterval = 5 * time.Minute
+
+// Run 立即生成一次 之后每interval生成一次 直到ctx取消 生成失败只记录日志
+// interval不大于0时使用DefaultInterval
+func (generator *Generator) Run(ctx context.Context, dir string, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := generator.Generate(dir); err != nil {
+			fyne.LogError("generate status page failed", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
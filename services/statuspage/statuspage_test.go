@@ -0,0 +1,125 @@
+package statuspage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/flyflyhe/httpMonitorGui/services/group"
+	"github.com/flyflyhe/httpMonitorGui/services/history"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuild(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+
+	now := time.Date(2022, 6, 10, 12, 0, 0, 0, time.Local)
+	check := func(url string, at time.Time, direct, proxied string) {
+		assert.Nil(t, store.AddRecords(
+			history.Record{Time: at, Source: "local", Url: url, Result: direct},
+			history.Record{Time: at, Source: "local", Url: url, Proxy: "http://u:p@1.2.3.4:80", Result: proxied},
+		))
+	}
+	//a 昨天故障10分钟后恢复 今天一次代理失败
+	yesterday := now.AddDate(0, 0, -1)
+	check("https://a.com", yesterday, "success", "success")
+	check("https://a.com", yesterday.Add(time.Minute), "timeout", "timeout")
+	check("https://a.com", yesterday.Add(5*time.Minute), "502 Bad Gateway", "success")
+	check("https://a.com", yesterday.Add(10*time.Minute), "success", "success")
+	check("https://a.com", now.Add(-time.Hour), "success", "timeout")
+	check("https://a.com", now.Add(-time.Minute), "success", "success")
+	//b 当前故障
+	check("https://b.com", now.Add(-2*time.Minute), "success", "success")
+	check("https://b.com", now.Add(-time.Minute), "timeout", "timeout")
+	//已删除的地址和窗口外的记录不计入
+	check("https://deleted.com", now, "timeout", "timeout")
+	check("https://a.com", now.AddDate(0, 0, -100), "timeout", "timeout")
+
+	index := map[string]group.Meta{"https://a.com": {Url: "https://a.com", Group: "官网"}}
+	//公开页面去掉账号密码与查询参数
+	c := "https://user:pw@c.com/health?token=abc#top"
+	page, err := Build(store, []string{"https://a.com", "https://b.com", c}, index, now, Options{Incidents: 2})
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultTitle, page.Title)
+	assert.Equal(t, StateDown, page.State)
+
+	assert.Len(t, page.Groups, 2)
+	assert.Equal(t, "官网", page.Groups[0].Name)
+	assert.Equal(t, StateOk, page.Groups[0].State)
+	a := page.Groups[0].Urls[0]
+	assert.Len(t, a.Days, DefaultDays)
+	assert.True(t, a.Days[DefaultDays-1].Date.Equal(time.Date(2022, 6, 10, 0, 0, 0, 0, time.Local)))
+	assert.Equal(t, Day{Date: a.Days[DefaultDays-2].Date, Checks: 4, Up: 3, Degraded: 1}, a.Days[DefaultDays-2])
+	assert.Equal(t, StateDown, a.Days[DefaultDays-2].State())
+	assert.Equal(t, StateDegraded, a.Days[DefaultDays-1].State())
+	assert.Equal(t, StateUnknown, a.Days[0].State())
+	assert.InDelta(t, 5.0/6, a.Uptime(), 0.0001)
+	assert.True(t, a.LastCheck.Equal(now.Add(-time.Minute)))
+
+	assert.Equal(t, group.Ungrouped, page.Groups[1].Name)
+	assert.Equal(t, StateDown, page.Groups[1].State)
+	assert.Equal(t, StateUnknown, page.Groups[1].Urls[1].State)
+	assert.Equal(t, "https://c.com/health", page.Groups[1].Urls[1].Url)
+
+	//最新的在前 只保留2个
+	assert.Len(t, page.Incidents, 2)
+	b := page.Incidents[0]
+	assert.Equal(t, "https://b.com", b.Url)
+	assert.True(t, b.Ongoing())
+	assert.Equal(t, time.Minute, b.Duration(now))
+	assert.Equal(t, "超时", b.Cause)
+	degraded := page.Incidents[1]
+	assert.Equal(t, StateDegraded, degraded.State)
+	assert.Equal(t, time.Hour-time.Minute, degraded.Duration(now))
+	assert.Equal(t, "官网", degraded.Group)
+
+	dir := filepath.Join(t.TempDir(), "status")
+	assert.Nil(t, WriteDir(dir, page))
+	html, err := os.ReadFile(filepath.Join(dir, FileName))
+	assert.Nil(t, err)
+	text := string(html)
+	assert.Contains(t, text, "服务故障")
+	assert.Contains(t, text, "https://c.com/health")
+	assert.Contains(t, text, "未恢复")
+	assert.False(t, strings.Contains(text, "1.2.3.4"))
+	assert.False(t, strings.Contains(text, "pw@"))
+	assert.False(t, strings.Contains(text, "token"))
+	entries, _ := os.ReadDir(dir)
+	assert.Len(t, entries, 1)
+
+	//原始记录清理后可用率仍来自每日汇总 故障只统计最近的原始记录
+	_, err = store.Prune(now.Add(-time.Minute))
+	assert.Nil(t, err)
+	page, err = Build(store, []string{"https://a.com"}, index, now, Options{})
+	assert.Nil(t, err)
+	a = page.Groups[0].Urls[0]
+	assert.Equal(t, 4, a.Days[DefaultDays-2].Checks)
+	assert.InDelta(t, 5.0/6, a.Uptime(), 0.0001)
+	assert.Empty(t, page.Incidents)
+}
+
+func TestPublicUrl(t *testing.T) {
+	assert.Equal(t, "https://a.com/path", publicUrl("https://u:p@a.com/path?key=1#x"))
+	assert.Equal(t, "https://a.com/%zz", publicUrl("https://u:p@a.com/%zz?key=1"))
+}
+
+func TestGenerate(t *testing.T) {
+	store, err := history.Open(filepath.Join(t.TempDir(), "history.db"))
+	assert.Nil(t, err)
+	defer store.Close()
+	assert.Nil(t, store.AddRecords(history.Record{Time: time.Now(), Source: "local", Url: "https://a.com", Result: "success"}))
+
+	//服务断开时使用历史记录中的url
+	generator := &Generator{Store: store, Urls: func() ([]string, error) {
+		return nil, os.ErrDeadlineExceeded
+	}}
+	dir := t.TempDir()
+	assert.Nil(t, generator.Generate(dir))
+	body, err := os.ReadFile(filepath.Join(dir, FileName))
+	assert.Nil(t, err)
+	assert.Contains(t, string(body), "https://a.com")
+}
@@ -0,0 +1,184 @@
+package transfer
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/flyflyhe/httpMonitorGui/services/proxy"
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+)
+
+type Action int
+
+const (
+	// ActionAdd 服务中不存在 将添加
+	ActionAdd Action = iota
+	// ActionChange 地址已存在但间隔不同 将修改间隔
+	ActionChange
+	// ActionSame 与服务中一致 跳过
+	ActionSame
+	// ActionConflict 格式错误或文件内重复且不一致 跳过
+	ActionConflict
+)
+
+func (action Action) String() string {
+	switch action {
+	case ActionAdd:
+		return "新增"
+	case ActionChange:
+		return "修改"
+	case ActionSame:
+		return "不变"
+	}
+	return "冲突"
+}
+
+// Item 导入预览中的一项 Proxy为true时Value是代理
+type Item struct {
+	Action      Action
+	Proxy       bool
+	Value       string
+	Interval    int32
+	OldInterval int32
+	Reason      string
+}
+
+func (item Item) String() string {
+	if item.Proxy {
+		text := "[" + item.Action.String() + "] 代理 " + proxy.Redact(item.Value)
+		if item.Reason != "" {
+			text += " " + item.Reason
+		}
+		return text
+	}
+
+	text := "[" + item.Action.String() + "] " + item.Value
+	switch item.Action {
+	case ActionAdd, ActionSame:
+		text += " " + rpc.FormatInterval(item.Interval)
+	case ActionChange:
+		text += " " + rpc.FormatInterval(item.OldInterval) + "->" + rpc.FormatInterval(item.Interval)
+	case ActionConflict:
+		text += " " + item.Reason
+	}
+	return text
+}
+
+// Plan 导入预览
+type Plan struct {
+	Items []Item
+}
+
+// Count 某种操作的数量
+func (plan *Plan) Count(action Action) int {
+	n := 0
+	for _, item := range plan.Items {
+		if item.Action == action {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary eg:新增3 修改1 不变2 冲突0
+func (plan *Plan) Summary() string {
+	var parts []string
+	for _, action := range []Action{ActionAdd, ActionChange, ActionSame, ActionConflict} {
+		parts = append(parts, action.String()+strconv.Itoa(plan.Count(action)))
+	}
+	return strings.Join(parts, " ")
+}
+
+// NewPlan 对比配置与服务当前状态 不修改服务
+func NewPlan(backend rpc.MonitorBackend, config *Config) (*Plan, error) {
+	urlInterval, err := backend.ListUrlInterval()
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := backend.ListProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	seen := make(map[string]int32)
+	for _, u := range config.Urls {
+		item := Item{Value: u.Url}
+		//与命令行和接口一致 没有间隔时使用默认间隔
+		if strings.TrimSpace(u.Interval) == "" {
+			u.Interval = rpc.DefaultInterval.String()
+		}
+		interval, err := rpc.ParseInterval(u.Interval)
+		if err == nil {
+			err = rpc.ValidateUrl(u.Url)
+		}
+		item.Interval = interval
+		if err != nil {
+			item.Action, item.Reason = ActionConflict, err.Error()
+		} else if first, ok := seen[u.Url]; ok {
+			if first == interval {
+				continue
+			}
+			item.Action, item.Reason = ActionConflict, "文件内重复 间隔不一致"
+		} else if old, ok := urlInterval[u.Url]; !ok {
+			item.Action = ActionAdd
+		} else if old != interval {
+			item.Action, item.OldInterval = ActionChange, old
+		} else {
+			item.Action = ActionSame
+		}
+		if item.Action != ActionConflict {
+			seen[u.Url] = interval
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	existing := make(map[string]bool, len(proxies))
+	for _, raw := range proxies {
+		existing[raw] = true
+	}
+	for _, raw := range config.Proxies {
+		raw = strings.TrimSpace(raw)
+		item := Item{Proxy: true, Value: raw}
+		if _, err := proxy.Parse(raw); err != nil {
+			item.Action, item.Reason = ActionConflict, err.Error()
+		} else if existing[raw] {
+			item.Action = ActionSame
+		} else {
+			item.Action = ActionAdd
+			existing[raw] = true
+		}
+		plan.Items = append(plan.Items, item)
+	}
+
+	return plan, nil
+}
+
+// Apply 执行新增与修改 出错的项不影响其他项 返回合并的错误
+func (plan *Plan) Apply(backend rpc.MonitorBackend) error {
+	var failed []string
+	for _, item := range plan.Items {
+		if item.Action != ActionAdd && item.Action != ActionChange {
+			continue
+		}
+		var err error
+		if item.Proxy {
+			err = backend.SetProxy(item.Value)
+		} else {
+			err = backend.SetUrl(item.Value, item.Interval)
+		}
+		if err != nil {
+			value := item.Value
+			if item.Proxy {
+				value = proxy.Redact(value)
+			}
+			failed = append(failed, value+":"+err.Error())
+		}
+	}
+	if len(failed) > 0 {
+		return errors.New("部分导入失败\n" + strings.Join(failed, "\n"))
+	}
+
+	return nil
+}
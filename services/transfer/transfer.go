@@ -0,0 +1,149 @@
+package transfer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/flyflyhe/httpMonitorGui/services/rpc"
+	"gopkg.in/yaml.v3"
+)
+
+type Format string
+
+const (
+	FormatYAML Format = "yaml"
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+)
+
+// Extensions 可导入的文件扩展名
+var Extensions = []string{".yaml", ".yml", ".json", ".csv"}
+
+// FormatOf 按扩展名判断格式
+func FormatOf(path string) (Format, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		return FormatYAML, nil
+	case ".json":
+		return FormatJSON, nil
+	case ".csv":
+		return FormatCSV, nil
+	}
+
+	return "", errors.New("不支持的文件格式:" + path)
+}
+
+// UrlConfig Interval为时长文字如"30s" 也可以是毫秒数
+type UrlConfig struct {
+	Url      string `yaml:"url" json:"url"`
+	Interval string `yaml:"interval" json:"interval"`
+}
+
+// Config 导入导出的地址与代理配置
+type Config struct {
+	Urls    []UrlConfig `yaml:"urls" json:"urls"`
+	Proxies []string    `yaml:"proxies" json:"proxies"`
+}
+
+// Export 读取服务当前的地址与代理 按地址排序
+func Export(backend rpc.MonitorBackend) (*Config, error) {
+	urlInterval, err := backend.ListUrlInterval()
+	if err != nil {
+		return nil, err
+	}
+	proxies, err := backend.ListProxy()
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{Proxies: proxies}
+	for url, interval := range urlInterval {
+		config.Urls = append(config.Urls, UrlConfig{Url: url, Interval: rpc.FormatInterval(interval)})
+	}
+	sort.Slice(config.Urls, func(i, j int) bool {
+		return config.Urls[i].Url < config.Urls[j].Url
+	})
+
+	return config, nil
+}
+
+// Encode csv每行为 类型,值,间隔 类型为url或proxy
+func Encode(config *Config, format Format) ([]byte, error) {
+	switch format {
+	case FormatYAML:
+		return yaml.Marshal(config)
+	case FormatJSON:
+		return json.MarshalIndent(config, "", "  ")
+	case FormatCSV:
+		var buf bytes.Buffer
+		writer := csv.NewWriter(&buf)
+		records := [][]string{{"type", "value", "interval"}}
+		for _, u := range config.Urls {
+			records = append(records, []string{"url", u.Url, u.Interval})
+		}
+		for _, proxy := range config.Proxies {
+			records = append(records, []string{"proxy", proxy, ""})
+		}
+		if err := writer.WriteAll(records); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	return nil, errors.New("不支持的文件格式:" + string(format))
+}
+
+func Decode(data []byte, format Format) (*Config, error) {
+	config := &Config{}
+	switch format {
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, config); err != nil {
+			return nil, errors.New("yaml格式错误:" + err.Error())
+		}
+	case FormatJSON:
+		if err := json.Unmarshal(data, config); err != nil {
+			return nil, errors.New("json格式错误:" + err.Error())
+		}
+	case FormatCSV:
+		reader := csv.NewReader(bytes.NewReader(data))
+		reader.FieldsPerRecord = -1
+		reader.TrimLeadingSpace = true
+		for line := 1; ; line++ {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, errors.New("csv格式错误:" + err.Error())
+			}
+			if len(record) < 2 {
+				return nil, errors.New("csv第" + strconv.Itoa(line) + "行至少需要类型和值两列")
+			}
+			switch kind := strings.ToLower(strings.TrimSpace(record[0])); kind {
+			case "type":
+				//表头
+			case "url":
+				u := UrlConfig{Url: strings.TrimSpace(record[1])}
+				if len(record) > 2 {
+					u.Interval = strings.TrimSpace(record[2])
+				}
+				config.Urls = append(config.Urls, u)
+			case "proxy":
+				config.Proxies = append(config.Proxies, strings.TrimSpace(record[1]))
+			default:
+				return nil, errors.New("csv第" + strconv.Itoa(line) + "行类型错误:" + kind)
+			}
+		}
+	default:
+		return nil, errors.New("不支持的文件格式:" + string(format))
+	}
+
+	return config, nil
+}